@@ -0,0 +1,19 @@
+//go:build linux
+
+package edge_tts_go
+
+import (
+	"io/fs"
+	"syscall"
+	"time"
+)
+
+// accessTime returns the later of the access and modification times of a file.
+func accessTime(info fs.FileInfo) time.Time {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		if atime := time.Unix(int64(st.Atim.Sec), int64(st.Atim.Nsec)); atime.After(info.ModTime()) {
+			return atime
+		}
+	}
+	return info.ModTime()
+}
@@ -0,0 +1,13 @@
+//go:build !linux && !darwin
+
+package edge_tts_go
+
+import (
+	"io/fs"
+	"time"
+)
+
+// accessTime returns the modification time of a file, the access time is not read on this platform.
+func accessTime(info fs.FileInfo) time.Time {
+	return info.ModTime()
+}
@@ -0,0 +1,120 @@
+package edge_tts_go
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/pp-group/edge-tts-go/biz/service/tts/edge"
+)
+
+// audioHeadSize is how much of the audio of each message is kept to read its RIFF header.
+const audioHeadSize = 256
+
+// AudioInfo describes audio as read from its mp3 frames or its RIFF header. Duration is in seconds,
+// Bitrate in bits per second, averaged over the frames of variable bitrate audio.
+type AudioInfo struct {
+	Duration   float64 `json:"duration"`
+	Bitrate    int     `json:"bitrate"`
+	SampleRate int     `json:"sample_rate"`
+	Channels   int     `json:"channels"`
+	Size       int64   `json:"size"`
+}
+
+// ParseAudioInfo reads the info of audio in an output format: mp3 from its frames, wav from its RIFF header
+// and raw pcm from the name of the format. Other formats are estimated from the name of the format.
+func ParseAudioInfo(format string, data []byte) (AudioInfo, error) {
+	switch {
+	case isMP3(format):
+		m := newMP3Writer(io.Discard)
+		if _, err := m.Write(data); err != nil {
+			return AudioInfo{}, err
+		}
+		m.endStream()
+		info := m.streams[0].info()
+		info.Size = int64(len(data))
+		return info, nil
+	case strings.HasPrefix(format, "riff-"):
+		return parseRIFF(data)
+	}
+	return estimateAudioInfo(format, int64(len(data))), nil
+}
+
+// estimateAudioInfo tells the info of size bytes of audio from the name of its format.
+func estimateAudioInfo(format string, size int64) AudioInfo {
+	return AudioInfo{
+		Duration:   edge.AudioSeconds(format, size),
+		Bitrate:    edge.FormatBitrate(format),
+		SampleRate: edge.FormatSampleRate(format),
+		Channels:   edge.FormatChannels(format),
+		Size:       size,
+	}
+}
+
+// parseRIFF reads the fmt chunk of a wav file, the duration is told by the bytes after the header
+// as the service streams wav before its size is known.
+func parseRIFF(data []byte) (AudioInfo, error) {
+	if len(data) < 12 || string(data[:4]) != "RIFF" || string(data[8:12]) != "WAVE" {
+		return AudioInfo{}, errors.New("not a RIFF WAVE header")
+	}
+	info := AudioInfo{Size: int64(len(data))}
+	byteRate := 0
+	for offset := 12; offset+8 <= len(data); {
+		id, size := string(data[offset:offset+4]), int(binary.LittleEndian.Uint32(data[offset+4:]))
+		body := offset + 8
+		switch id {
+		case "fmt ":
+			if body+16 > len(data) {
+				return AudioInfo{}, errors.New("RIFF fmt chunk is truncated")
+			}
+			info.Channels = int(binary.LittleEndian.Uint16(data[body+2:]))
+			info.SampleRate = int(binary.LittleEndian.Uint32(data[body+4:]))
+			byteRate = int(binary.LittleEndian.Uint32(data[body+8:]))
+			info.Bitrate = byteRate * 8
+		case "data":
+			if byteRate == 0 {
+				return AudioInfo{}, errors.New("RIFF data chunk before its fmt chunk")
+			}
+			info.Duration = float64(len(data)-body) / float64(byteRate)
+			return info, nil
+		}
+		// chunks are padded to an even size
+		offset = body + size + size%2
+	}
+	return AudioInfo{}, errors.New("RIFF data chunk not found")
+}
+
+// chunkAudioInfo tells the info of the audio of one message from its first bytes and its size.
+func chunkAudioInfo(format string, head []byte, size int64) AudioInfo {
+	if size == 0 {
+		return AudioInfo{}
+	}
+	if strings.HasPrefix(format, "riff-") {
+		if info, err := parseRIFF(head); err == nil {
+			// the duration of the header is of the head only
+			info.Duration = info.Duration + float64(size-int64(len(head)))*8/float64(info.Bitrate)
+			info.Size = size
+			return info
+		}
+	}
+	return estimateAudioInfo(format, size)
+}
+
+// mergeAudioInfo is the info of the messages stored one after another as size bytes.
+func mergeAudioInfo(chunks []AudioInfo, size int64) AudioInfo {
+	merged := AudioInfo{Size: size}
+	var audio int64
+	for _, chunk := range chunks {
+		merged.Duration += chunk.Duration
+		audio += chunk.Size
+		if merged.SampleRate == 0 {
+			merged.SampleRate, merged.Channels = chunk.SampleRate, chunk.Channels
+		}
+	}
+	if merged.Duration > 0 {
+		merged.Bitrate = int(math.Round(float64(audio*8) / merged.Duration))
+	}
+	return merged
+}
@@ -0,0 +1,91 @@
+package edge_tts_go
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pp-group/edge-tts-go/biz/service/tts/edge"
+)
+
+// testWAV is a wav header of 24kHz 16bit mono pcm followed by size bytes of audio.
+func testWAV(size int) []byte {
+	var b bytes.Buffer
+	b.WriteString("RIFF")
+	binary.Write(&b, binary.LittleEndian, uint32(36+size))
+	b.WriteString("WAVEfmt ")
+	binary.Write(&b, binary.LittleEndian, []uint32{16})
+	binary.Write(&b, binary.LittleEndian, []uint16{1, 1})
+	binary.Write(&b, binary.LittleEndian, []uint32{24000, 48000})
+	binary.Write(&b, binary.LittleEndian, []uint16{2, 16})
+	b.WriteString("data")
+	// unknown while streaming
+	binary.Write(&b, binary.LittleEndian, uint32(0xFFFFFFFF))
+	b.Write(make([]byte, size))
+	return b.Bytes()
+}
+
+func TestParseAudioInfo(t *testing.T) {
+	mp3 := bytes.Join([][]byte{testInfoFrame("Xing"), bytes.Repeat(testFrame(1), 50)}, nil)
+	info, err := ParseAudioInfo(edge.DefaultOutputFormat, mp3)
+	if err != nil || info != (AudioInfo{Duration: 1.2, Bitrate: 48000, SampleRate: 24000, Channels: 1, Size: 51 * 144}) {
+		t.Errorf("mp3 info = %+v, err: %v", info, err)
+	}
+
+	wav := testWAV(72000)
+	info, err = ParseAudioInfo("riff-24khz-16bit-mono-pcm", wav)
+	if err != nil || info != (AudioInfo{Duration: 1.5, Bitrate: 384000, SampleRate: 24000, Channels: 1, Size: 72044}) {
+		t.Errorf("wav info = %+v, err: %v", info, err)
+	}
+	if _, err := ParseAudioInfo("riff-24khz-16bit-mono-pcm", []byte("RIFF")); err == nil {
+		t.Errorf("truncated wav should fail")
+	}
+	if info := chunkAudioInfo("riff-24khz-16bit-mono-pcm", wav[:audioHeadSize], int64(len(wav))); info.Duration != 1.5 || info.Size != 72044 {
+		t.Errorf("wav chunk info = %+v", info)
+	}
+
+	info, err = ParseAudioInfo("raw-22050hz-16bit-mono-pcm", make([]byte, 44100))
+	if err != nil || info != (AudioInfo{Duration: 1, Bitrate: 352800, SampleRate: 22050, Channels: 1, Size: 44100}) {
+		t.Errorf("pcm info = %+v, err: %v", info, err)
+	}
+
+	merged := mergeAudioInfo([]AudioInfo{
+		{Duration: 1, Bitrate: 48000, SampleRate: 24000, Channels: 1, Size: 6000},
+		{},
+		{Duration: 3, Bitrate: 64000, SampleRate: 24000, Channels: 1, Size: 24000},
+	}, 30144)
+	if merged != (AudioInfo{Duration: 4, Bitrate: 60000, SampleRate: 24000, Channels: 1, Size: 30144}) {
+		t.Errorf("merged info = %+v", merged)
+	}
+}
+
+func TestSynthesizeCachedAudioInfo(t *testing.T) {
+	folder := t.TempDir()
+	service, err := NewLocalSpeechService(folder)
+	if err != nil {
+		t.Fatalf("NewLocalSpeechService fail, err: %v", err)
+	}
+	req := Request{Text: "measured"}
+	c, _ := edge.NewCommunicate(req.Text)
+	if err := os.WriteFile(filepath.Join(folder, Key(c)), make([]byte, 6144), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	chunks := []AudioInfo{{Duration: 1.008, Bitrate: 48000, SampleRate: 24000, Channels: 1, Size: 6048}}
+	m := newMetadata(c, &Result{Key: Key(c), Format: c.OutputFormat, Size: 6144, Audio: mergeAudioInfo(chunks, 6144), Chunks: chunks}, false)
+	data, _ := json.Marshal(m)
+	if err := os.WriteFile(filepath.Join(folder, MetadataKey(Key(c))), data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := service.Synthesize(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Synthesize fail, err: %v", err)
+	}
+	if !result.Cached || result.Duration != 1.008 || result.Audio.Size != 6144 || len(result.Chunks) != 1 || result.Chunks[0] != chunks[0] {
+		t.Errorf("unexpected result: %+v", result)
+	}
+}
@@ -0,0 +1,396 @@
+package edge_tts_go
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+
+	storage "github.com/pp-group/file-helper/storage"
+
+	"github.com/pp-group/edge-tts-go/biz/service/tts/edge"
+)
+
+// Chapter is one chapter of a book.
+type Chapter struct {
+	Title string
+	Text  string
+	// InputMode is how Text is read, see edge.WithInputMode, chapters of an epub are html
+	InputMode string
+}
+
+const englishNumberWords = `one|two|three|four|five|six|seven|eight|nine|ten|eleven|twelve|thirteen|fourteen|fifteen|` +
+	`sixteen|seventeen|eighteen|nineteen|(?:twenty|thirty|forty|fifty|sixty|seventy|eighty|ninety)(?:-[a-z]+)?`
+
+var chapterHeadingPattern = regexp.MustCompile(`(?m)^[ \t　]*(?:` +
+	`第[0-9零〇一二两三四五六七八九十百千]+[章回节卷部篇集][^\n]{0,40}` +
+	`|(?i:(?:chapter|part|book)\s+(?:[0-9]+|[ivxlcdm]+|` + englishNumberWords + `)\b)[^\n]{0,60}` +
+	`|(?i:prologue|epilogue|preface|introduction)` +
+	`|序章|序言|楔子|尾声|后记|前言` +
+	`)[ \t]*$`)
+
+// DetectChapters splits a plain text document at its chapter headings, such as "Chapter 1", "CHAPTER IV"
+// or "第一章 重逢". The heading stays at the start of the chapter text so that it is read out. Text before
+// the first heading becomes an untitled chapter, a document without headings is a single chapter.
+func DetectChapters(text string) []Chapter {
+	var chapters []Chapter
+	locs := chapterHeadingPattern.FindAllStringIndex(text, -1)
+	if len(locs) == 0 || strings.TrimSpace(text[:locs[0][0]]) != "" {
+		end := len(text)
+		if len(locs) > 0 {
+			end = locs[0][0]
+		}
+		if body := strings.TrimSpace(text[:end]); body != "" {
+			chapters = append(chapters, Chapter{Text: body})
+		}
+	}
+	for i, loc := range locs {
+		end := len(text)
+		if i+1 < len(locs) {
+			end = locs[i+1][0]
+		}
+		chapters = append(chapters, Chapter{
+			Title: strings.TrimSpace(text[loc[0]:loc[1]]),
+			Text:  strings.TrimSpace(text[loc[0]:end]),
+		})
+	}
+	return chapters
+}
+
+var (
+	xhtmlTitlePattern    = regexp.MustCompile(`(?is)<h[1-3][^>]*>(.*?)</h[1-3]\s*>|<title[^>]*>(.*?)</title\s*>`)
+	xhtmlBodyPattern     = regexp.MustCompile(`(?is)<body[^>]*>(.*)</body\s*>`)
+	xhtmlTagPattern      = regexp.MustCompile(`(?s)<[^>]*>`)
+	xhtmlSpacesPattern   = regexp.MustCompile(`\s+`)
+	epubDocumentSuffixes = []string{".xhtml", ".html", ".htm"}
+)
+
+// ReadEPUB reads the chapters of an epub in reading order, every document of the spine that has text is a chapter.
+func ReadEPUB(r io.ReaderAt, size int64) ([]Chapter, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("open epub err. %s", err.Error())
+	}
+	files := map[string]*zip.File{}
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+	readFile := func(name string) ([]byte, error) {
+		f, ok := files[name]
+		if !ok {
+			return nil, fmt.Errorf("epub has no %s", name)
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		return io.ReadAll(rc)
+	}
+
+	data, err := readFile("META-INF/container.xml")
+	if err != nil {
+		return nil, err
+	}
+	var container struct {
+		Rootfiles []struct {
+			FullPath string `xml:"full-path,attr"`
+		} `xml:"rootfiles>rootfile"`
+	}
+	if err := xml.Unmarshal(data, &container); err != nil {
+		return nil, fmt.Errorf("decode epub container err. %s", err.Error())
+	}
+	if len(container.Rootfiles) == 0 {
+		return nil, errors.New("epub container has no rootfile")
+	}
+
+	opfPath := container.Rootfiles[0].FullPath
+	data, err = readFile(opfPath)
+	if err != nil {
+		return nil, err
+	}
+	var pkg struct {
+		Items []struct {
+			ID        string `xml:"id,attr"`
+			Href      string `xml:"href,attr"`
+			MediaType string `xml:"media-type,attr"`
+		} `xml:"manifest>item"`
+		Itemrefs []struct {
+			IDRef string `xml:"idref,attr"`
+		} `xml:"spine>itemref"`
+	}
+	if err := xml.Unmarshal(data, &pkg); err != nil {
+		return nil, fmt.Errorf("decode epub package err. %s", err.Error())
+	}
+	hrefs := map[string]string{}
+	for _, item := range pkg.Items {
+		hrefs[item.ID] = item.Href
+	}
+
+	var chapters []Chapter
+	for _, ref := range pkg.Itemrefs {
+		href, ok := hrefs[ref.IDRef]
+		if !ok || !isEPUBDocument(href) {
+			continue
+		}
+		doc, err := readFile(path.Join(path.Dir(opfPath), href))
+		if err != nil {
+			return nil, err
+		}
+		body := string(doc)
+		if m := xhtmlBodyPattern.FindStringSubmatch(body); m != nil {
+			body = m[1]
+		}
+		if plainXHTML(body) == "" {
+			continue
+		}
+		title := ""
+		if m := xhtmlTitlePattern.FindStringSubmatch(string(doc)); m != nil {
+			title = plainXHTML(m[1] + m[2])
+		}
+		chapters = append(chapters, Chapter{Title: title, Text: body, InputMode: edge.InputModeHTML})
+	}
+	return chapters, nil
+}
+
+func isEPUBDocument(href string) bool {
+	for _, suffix := range epubDocumentSuffixes {
+		if strings.HasSuffix(strings.ToLower(href), suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+func plainXHTML(s string) string {
+	return strings.TrimSpace(xhtmlSpacesPattern.ReplaceAllString(xhtmlTagPattern.ReplaceAllString(s, " "), " "))
+}
+
+// AudiobookChapter is the record of one synthesized chapter in the manifest, times are in seconds.
+type AudiobookChapter struct {
+	Index    int     `json:"index"`
+	Title    string  `json:"title"`
+	FileName string  `json:"file_name"`
+	Size     int64   `json:"size"`
+	Duration float64 `json:"duration"`
+	// Start is where the chapter starts in the merged file
+	Start float64 `json:"start"`
+}
+
+// AudiobookManifest describes the objects produced for a book, it is stored as <name>.json.
+type AudiobookManifest struct {
+	Name     string             `json:"name"`
+	Chapters []AudiobookChapter `json:"chapters"`
+	Duration float64            `json:"duration"`
+	// Merged is the file name of the whole book with chapter markers, if requested
+	Merged string `json:"merged,omitempty"`
+}
+
+// AudiobookOptions configures GenAudiobook.
+type AudiobookOptions struct {
+	// Name prefixes every object of the book, chapters are <name>_001.mp3 and so on
+	Name string
+	// Options are used for the Communicate of every chapter
+	Options []edge.Option
+	// Merge also stores the whole book as <name>.mp3 with ID3 chapter markers
+	Merge bool
+}
+
+// GenAudiobook synthesizes every chapter through Speech into its own object of the storage,
+// then stores the manifest and, if requested, the merged book.
+func GenAudiobook(s storage.IStorage, helper storage.ParamsHelper, folder string, chapters []Chapter, opts AudiobookOptions) (*AudiobookManifest, error) {
+	if opts.Name == "" {
+		return nil, errors.New("audiobook must have a name")
+	}
+	if len(chapters) == 0 {
+		return nil, errors.New("audiobook has no chapters")
+	}
+	if opts.Merge && edge.GetOutputFormatByOption(opts.Options) != "" && !isMP3(edge.GetOutputFormatByOption(opts.Options)) {
+		return nil, errors.New("merged audiobook needs an mp3 output format")
+	}
+
+	manifest := &AudiobookManifest{Name: opts.Name}
+	var book *mergedBook
+	if opts.Merge {
+		manifest.Merged = opts.Name + ".mp3"
+		var err error
+		if book, err = newMergedBook(s, helper, folder, manifest.Merged, chapters); err != nil {
+			return nil, err
+		}
+	}
+	for i, chapter := range chapters {
+		if err := genChapter(s, helper, folder, manifest, i, chapter, opts, book); err != nil {
+			if book != nil {
+				abortWrite(book.broker)
+			}
+			return nil, err
+		}
+	}
+	if book != nil {
+		if err := book.close(manifest.Chapters); err != nil {
+			return nil, err
+		}
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := writeObject(s, helper, folder, opts.Name+".json", data); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// genChapter synthesizes a chapter into its own object, adds it to the manifest and, when merging, to the book.
+func genChapter(s storage.IStorage, helper storage.ParamsHelper, folder string, manifest *AudiobookManifest, i int, chapter Chapter, opts AudiobookOptions, book *mergedBook) error {
+	options := opts.Options
+	if chapter.InputMode != "" {
+		options = append(append([]edge.Option{}, options...), edge.WithInputMode(chapter.InputMode))
+	}
+	c, err := edge.NewCommunicate(chapter.Text, options...)
+	if err != nil {
+		return fmt.Errorf("chapter %d: %s", i+1, err.Error())
+	}
+	speech, err := NewSpeech(c, s, folder)
+	if err != nil {
+		return err
+	}
+	speech.FileName = fmt.Sprintf("%s_%03d.%s", opts.Name, i+1, c.FileExtension())
+
+	broker, err := newWriter(s, helper, folder, speech.FileName)
+	if err != nil {
+		return err
+	}
+	counter := &countingBroker{IWriteBroker: broker}
+	if book != nil {
+		counter.tee = book.mp3
+	}
+	if err := speech.gen(counter); err != nil {
+		return fmt.Errorf("chapter %d: %s", i+1, err.Error())
+	}
+	if book != nil {
+		if counter.teeErr != nil {
+			return fmt.Errorf("chapter %d: %s", i+1, counter.teeErr.Error())
+		}
+		book.mp3.endStream()
+	}
+
+	duration := speech.Audio.Duration
+	manifest.Chapters = append(manifest.Chapters, AudiobookChapter{
+		Index:    i + 1,
+		Title:    chapter.Title,
+		FileName: speech.FileName,
+		Size:     counter.size,
+		Duration: duration,
+		Start:    manifest.Duration,
+	})
+	manifest.Duration += duration
+	return nil
+}
+
+// mergedBook streams the chapters into the merged object as they are synthesized, they are joined frame
+// by frame under one Xing frame after the ID3 tag of the chapter markers. The size of the tag does not
+// depend on the times of the chapters, so it is written first and rewritten once they are known.
+type mergedBook struct {
+	broker storage.IWriteBroker
+	at     io.WriterAt
+	mp3    *mp3Writer
+}
+
+func newMergedBook(s storage.IStorage, helper storage.ParamsHelper, folder, name string, chapters []Chapter) (*mergedBook, error) {
+	broker, err := newWriter(s, helper, folder, name)
+	if err != nil {
+		return nil, err
+	}
+	at, ok := broker.(io.WriterAt)
+	if !ok {
+		abortWrite(broker)
+		return nil, fmt.Errorf("%T can not store a merged audiobook", s)
+	}
+	placeholders := make([]AudiobookChapter, len(chapters))
+	for i, chapter := range chapters {
+		placeholders[i] = AudiobookChapter{Index: i + 1, Title: chapter.Title}
+	}
+	tag := chapterTag(placeholders)
+	if _, err := broker.Write(tag); err != nil {
+		abortWrite(broker)
+		return nil, err
+	}
+	audio := &offsetWriter{Writer: broker, at: at, base: int64(len(tag))}
+	return &mergedBook{broker: broker, at: at, mp3: newMP3Writer(audio)}, nil
+}
+
+// close rewrites the tag with the times of the chapters and the Xing frame, and stores the book.
+func (book *mergedBook) close(chapters []AudiobookChapter) error {
+	if _, err := book.at.WriteAt(chapterTag(chapters), 0); err != nil {
+		abortWrite(book.broker)
+		return err
+	}
+	if err := book.mp3.finish(); err != nil {
+		abortWrite(book.broker)
+		return err
+	}
+	return closeWrite(book.broker)
+}
+
+// offsetWriter is a writer whose WriteAt offsets start base bytes into at.
+type offsetWriter struct {
+	io.Writer
+	at   io.WriterAt
+	base int64
+}
+
+func (w *offsetWriter) WriteAt(p []byte, off int64) (int, error) {
+	return w.at.WriteAt(p, w.base+off)
+}
+
+// countingBroker counts the bytes written to a broker and optionally copies them, the first error of
+// the copy is kept in teeErr.
+type countingBroker struct {
+	storage.IWriteBroker
+	size   int64
+	tee    io.Writer
+	teeErr error
+}
+
+func (broker *countingBroker) Write(p []byte) (int, error) {
+	n, err := broker.IWriteBroker.Write(p)
+	broker.size += int64(n)
+	if broker.tee != nil && broker.teeErr == nil {
+		_, broker.teeErr = broker.tee.Write(p[:n])
+	}
+	return n, err
+}
+
+// WriteAt rewrites what was written to the broker, the copy is left as it was.
+func (broker *countingBroker) WriteAt(p []byte, off int64) (int, error) {
+	at, ok := broker.IWriteBroker.(io.WriterAt)
+	if !ok {
+		return 0, errors.New("broker can not rewrite what was written")
+	}
+	return at.WriteAt(p, off)
+}
+
+// Abort discards the writes of the broker, see abortWrite.
+func (broker *countingBroker) Abort() error {
+	abortWrite(broker.IWriteBroker)
+	return nil
+}
+
+func writeObject(s storage.IStorage, helper storage.ParamsHelper, folder, name string, data []byte) error {
+	broker, err := newWriter(s, helper, folder, name)
+	if err != nil {
+		return err
+	}
+	if _, err := broker.Write(data); err != nil {
+		abortWrite(broker)
+		return err
+	}
+	return closeWrite(broker)
+}
@@ -0,0 +1,87 @@
+package edge_tts_go
+
+import (
+	"archive/zip"
+	"bytes"
+	"testing"
+)
+
+func TestDetectChapters(t *testing.T) {
+	text := "Foreword text.\n\nChapter 1 The Start\nIt begins.\n\nCHAPTER II\nIt goes on.\n第三章 重逢\n他们又见面了。"
+	chapters := DetectChapters(text)
+	titles := []string{"", "Chapter 1 The Start", "CHAPTER II", "第三章 重逢"}
+	if len(chapters) != len(titles) {
+		t.Fatalf("DetectChapters got %d chapters, want %d: %+v", len(chapters), len(titles), chapters)
+	}
+	for i, title := range titles {
+		if chapters[i].Title != title {
+			t.Errorf("chapter %d title = %q, want %q", i, chapters[i].Title, title)
+		}
+	}
+	if chapters[2].Text != "CHAPTER II\nIt goes on." {
+		t.Errorf("chapter text = %q", chapters[2].Text)
+	}
+}
+
+func TestReadEPUB(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	files := map[string]string{
+		"META-INF/container.xml": `<container><rootfiles><rootfile full-path="OEBPS/content.opf"/></rootfiles></container>`,
+		"OEBPS/content.opf": `<package><manifest><item id="c1" href="c1.xhtml" media-type="application/xhtml+xml"/>` +
+			`<item id="css" href="style.css" media-type="text/css"/><item id="c2" href="c2.xhtml" media-type="application/xhtml+xml"/></manifest>` +
+			`<spine><itemref idref="c2"/><itemref idref="css"/><itemref idref="c1"/></spine></package>`,
+		"OEBPS/c1.xhtml": `<html><body><h1>One</h1><p>First.</p></body></html>`,
+		"OEBPS/c2.xhtml": `<html><head><title>Two</title></head><body><p>Second.</p></body></html>`,
+	}
+	for name, content := range files {
+		w, _ := zw.Create(name)
+		w.Write([]byte(content))
+	}
+	zw.Close()
+
+	chapters, err := ReadEPUB(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("ReadEPUB fail, err: %v", err)
+	}
+	if len(chapters) != 2 || chapters[0].Title != "Two" || chapters[1].Title != "One" || chapters[1].Text != "<h1>One</h1><p>First.</p>" {
+		t.Errorf("unexpected chapters: %+v", chapters)
+	}
+}
+
+func TestMergedBook(t *testing.T) {
+	memory := NewMemoryStorage()
+	book, err := newMergedBook(memory, nil, "", "book.mp3", []Chapter{{Title: "One"}, {Title: "Two"}})
+	if err != nil {
+		t.Fatalf("newMergedBook fail, err: %v", err)
+	}
+	for _, chapter := range [][]byte{
+		bytes.Join([][]byte{testInfoFrame("Info"), testFrame(1), testFrame(2)}, nil),
+		bytes.Join([][]byte{testInfoFrame("Info"), testFrame(3)}, nil),
+	} {
+		if _, err := book.mp3.Write(chapter); err != nil {
+			t.Fatalf("Write fail, err: %v", err)
+		}
+		book.mp3.endStream()
+	}
+	chapters := []AudiobookChapter{
+		{Index: 1, Title: "One", Duration: 0.048},
+		{Index: 2, Title: "Two", Start: 0.048, Duration: 0.024},
+	}
+	if err := book.close(chapters); err != nil {
+		t.Fatalf("close fail, err: %v", err)
+	}
+
+	data, ok := memory.Bytes("book.mp3")
+	tag := chapterTag(chapters)
+	if !ok || !bytes.HasPrefix(data, tag) {
+		t.Fatalf("merged book does not start with the chapter tag")
+	}
+	audio := data[len(tag):]
+	if len(audio) != 4*144 || string(audio[13:17]) != "Info" || audio[13+11] != 3 {
+		t.Errorf("unexpected Xing frame: % x", audio[:32])
+	}
+	if !bytes.Equal(audio[3*144:], testFrame(3)) {
+		t.Errorf("last frame = % x", audio[3*144:3*144+8])
+	}
+}
@@ -0,0 +1,118 @@
+package edge_tts_go
+
+import (
+	"context"
+	"sync"
+
+	"golang.org/x/time/rate"
+
+	"github.com/pp-group/edge-tts-go/biz/service/tts/edge"
+)
+
+// BatchItem is one request of a batch, ID identifies it in the results, such as the row of a spreadsheet.
+type BatchItem struct {
+	ID string
+	Request
+}
+
+// BatchResult is the result of one item, Index is its position in the batch.
+type BatchResult struct {
+	ID     string
+	Index  int
+	Result *Result
+	Err    error
+}
+
+// BatchOptions bounds the work of a batch.
+type BatchOptions struct {
+	// Workers is the number of items synthesized at once, 4 by default
+	Workers int
+	// Rate is the number of syntheses started per second, 0 does not limit. Items already
+	// stored are not synthesized, so they do not wait for the limit.
+	Rate float64
+	// Burst is the number of syntheses started at once within the Rate, 1 by default
+	Burst int
+}
+
+func (o BatchOptions) withDefaults() BatchOptions {
+	if o.Workers <= 0 {
+		o.Workers = 4
+	}
+	if o.Burst <= 0 {
+		o.Burst = 1
+	}
+	return o
+}
+
+// Batch synthesizes items with a pool of workers and sends the result of every item in the order they
+// finish, the channel is closed after the last one and must be read until then or until ctx is done. Items
+// already stored are returned as Cached, so a batch that was interrupted resumes where it stopped when it is
+// run again. Once ctx is done, the remaining items are dropped or fail with its error and the channel is
+// closed, so a caller that stops reading cancels ctx.
+func (s *SpeechService) Batch(ctx context.Context, items []BatchItem, opts BatchOptions) <-chan BatchResult {
+	opts = opts.withDefaults()
+	var limiter *rate.Limiter
+	if opts.Rate > 0 {
+		limiter = rate.NewLimiter(rate.Limit(opts.Rate), opts.Burst)
+	}
+
+	results := make(chan BatchResult)
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < opts.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range indexes {
+				select {
+				case results <- s.batchItem(ctx, limiter, index, items[index]):
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+	feed:
+		for index := range items {
+			select {
+			case indexes <- index:
+			case <-ctx.Done():
+				break feed
+			}
+		}
+		close(indexes)
+		wg.Wait()
+		close(results)
+	}()
+	return results
+}
+
+func (s *SpeechService) batchItem(ctx context.Context, limiter *rate.Limiter, index int, item BatchItem) BatchResult {
+	result := BatchResult{ID: item.ID, Index: index}
+	if err := ctx.Err(); err != nil {
+		result.Err = err
+		return result
+	}
+	if limiter != nil && !s.stored(item.Request) {
+		if err := limiter.Wait(ctx); err != nil {
+			result.Err = err
+			return result
+		}
+	}
+	result.Result, result.Err = s.Synthesize(ctx, item.Request)
+	return result
+}
+
+// stored reports whether Synthesize would find the audio of req stored, errors are left to Synthesize.
+func (s *SpeechService) stored(req Request) bool {
+	if req.ForceRefresh {
+		return false
+	}
+	c, err := edge.NewCommunicate(req.Text, req.Options...)
+	if err != nil {
+		return false
+	}
+	_, ok, err := s.stat(Key(c))
+	return err == nil && ok
+}
@@ -0,0 +1,71 @@
+package edge_tts_go
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/pp-group/edge-tts-go/biz/service/tts/edge"
+)
+
+func TestBatch(t *testing.T) {
+	memoryStorage := NewMemoryStorage()
+	service := NewMemorySpeechService(memoryStorage)
+
+	var items []BatchItem
+	for i := 0; i < 20; i++ {
+		req := Request{Text: fmt.Sprintf("prompt %d", i)}
+		c, _ := edge.NewCommunicate(req.Text)
+		w, _ := memoryStorage.Writer(Key(c), nil)
+		w.Write(make([]byte, 6000))
+		w.Close()
+		items = append(items, BatchItem{ID: fmt.Sprintf("row-%d", i), Request: req})
+	}
+	items = append(items, BatchItem{ID: "bad", Request: Request{Text: "prompt 0", Subtitles: []string{"txt"}}})
+
+	// stored items do not wait for the rate, a wait would take 10s
+	start := time.Now()
+	seen := map[int]bool{}
+	for r := range service.Batch(context.Background(), items, BatchOptions{Workers: 3, Rate: 0.1}) {
+		seen[r.Index] = true
+		if r.ID != items[r.Index].ID {
+			t.Errorf("result %d has id %s", r.Index, r.ID)
+		}
+		if r.ID == "bad" {
+			if r.Err == nil {
+				t.Errorf("unknown subtitle format should fail")
+			}
+			continue
+		}
+		if r.Err != nil || !r.Result.Cached || r.Result.Size != 6000 {
+			t.Errorf("result %s = %+v, err: %v", r.ID, r.Result, r.Err)
+		}
+	}
+	if len(seen) != len(items) {
+		t.Errorf("%d results for %d items", len(seen), len(items))
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("stored items took %s", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for r := range service.Batch(ctx, items, BatchOptions{}) {
+		if r.Err != context.Canceled {
+			t.Errorf("result %s err: %v", r.ID, r.Err)
+		}
+	}
+
+	// the workers stop when the caller cancels without reading the rest
+	goroutines := runtime.NumGoroutine()
+	ctx, cancel = context.WithCancel(context.Background())
+	<-service.Batch(ctx, items, BatchOptions{Workers: 2})
+	cancel()
+	for deadline := time.Now().Add(5 * time.Second); runtime.NumGoroutine() > goroutines; time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d goroutines left after cancel, %d before the batch", runtime.NumGoroutine(), goroutines)
+		}
+	}
+}
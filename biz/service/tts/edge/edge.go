@@ -2,7 +2,10 @@ package edge
 
 import (
 	"bytes"
+	"context"
+	"crypto/sha256"
 	"encoding/binary"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"html"
@@ -10,6 +13,7 @@ import (
 	"regexp"
 	"runtime/debug"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/bytedance/sonic"
@@ -65,8 +69,49 @@ type Communicate struct {
 	//x-high
 	//default
 	Pitch string
+
+	// Style, StyleDegree and Role are rendered as mstts:express-as, see
+	// https://learn.microsoft.com/en-us/azure/cognitive-services/speech-service/speech-synthesis-markup-voice#speaking-styles-and-roles
+	Style       string
+	StyleDegree string
+	Role        string
+
+	// Normalizers rewrite numbers, dates, currency and so on before the text is escaped and split,
+	// by default they are picked by the locale of the voice.
+	Normalizers NormalizerChain
+
+	// InputMode is one of InputModeText, InputModeMarkdown and InputModeHTML,
+	// CodeBlocks tells whether code blocks in markdown and html are skipped or announced.
+	InputMode  string
+	CodeBlocks string
+
+	// Lexicon holds custom pronunciations, see SetGlobalLexicon for the ones shared by all Communicate
+	Lexicon *Lexicon
+
+	// SecondaryVoices maps a locale to the full name of the voice that reads runs of text in that language,
+	// they are only used when the voice is not multilingual.
+	SecondaryVoices map[string]string
+	Multilingual    bool
+
+	// SentencePause, ParagraphPause and ChunkPause are silences like 500ms, empty means the service decides
+	SentencePause  string
+	ParagraphPause string
+	ChunkPause     string
+	PauseMarkers   []PauseMarker
+
+	// SanitizePolicy tells what happens to control characters, emoji, zero-width characters and symbols,
+	// see SanitizeReport for what it changes in Text
+	SanitizePolicy SanitizePolicy
+
+	// Progress receives the progress of Stream
+	Progress ProgressFunc
+
+	// OutputFormat is the audio format asked from the service, see DefaultOutputFormat
+	OutputFormat string
+
 	Proxy string
-	op    chan map[string]interface{}
+	// cancel stops the connections of the last Stream
+	cancel context.CancelFunc
 
 	AudioDataIndex int
 }
@@ -76,6 +121,46 @@ type AudioData struct {
 	Index int
 }
 
+const (
+	BoundaryWord     = "WordBoundary"
+	BoundarySentence = "SentenceBoundary"
+)
+
+// Boundary is a word or a sentence of the audio, Offset is from the start of the whole audio.
+type Boundary struct {
+	Type     string        `json:"type"`
+	Offset   time.Duration `json:"offset"`
+	Duration time.Duration `json:"duration"`
+	Text     string        `json:"text"`
+}
+
+// boundaryText is an alias so the text of boundary events keeps the type it has always had.
+type boundaryText = struct {
+	Text         string `json:"Text"`
+	Length       int64  `json:"Length"`
+	BoundaryType string `json:"BoundaryType"`
+}
+
+// BoundaryEvent returns the message index and the boundary of a word or sentence event of StreamContext,
+// the offset is from the start of that message.
+func BoundaryEvent(event map[string]interface{}) (int, Boundary, bool) {
+	boundaryType, _ := event["type"].(string)
+	if boundaryType != BoundaryWord && boundaryType != BoundarySentence {
+		return 0, Boundary{}, false
+	}
+	idx, _ := event["index"].(int)
+	offset, _ := event["offset"].(int)
+	duration, _ := event["duration"].(int)
+	text, _ := event["text"].(boundaryText)
+	// the service counts in ticks of 100ns
+	return idx, Boundary{
+		Type:     boundaryType,
+		Offset:   time.Duration(offset) * 100,
+		Duration: time.Duration(duration) * 100,
+		Text:     text.Text,
+	}, true
+}
+
 type UnknownResponse struct {
 	Message string
 }
@@ -99,6 +184,21 @@ func NewCommunicate(text string, opts ...Option) (*Communicate, error) {
 	volume := GetVolumeByOption(opts)
 	proxy := GetProxyByOption(opts)
 	pitch := GetPitchByOption(opts)
+	style := GetStyleByOption(opts)
+	styleDegree := GetStyleDegreeByOption(opts)
+	role := GetRoleByOption(opts)
+	normalizers, hasNormalizers := GetNormalizersByOption(opts)
+	inputMode := GetInputModeByOption(opts)
+	codeBlocks := GetCodeBlocksByOption(opts)
+	lexicon := GetLexiconByOption(opts)
+	multilingual := GetMultilingualByOption(opts)
+	sentencePause := GetSentencePauseByOption(opts)
+	paragraphPause := GetParagraphPauseByOption(opts)
+	chunkPause := GetChunkPauseByOption(opts)
+	pauseMarkers := GetPauseMarkersByOption(opts)
+	sanitizePolicy := GetSanitizePolicyByOption(opts)
+	progress := GetProgressByOption(opts)
+	outputFormat := GetOutputFormatByOption(opts)
 	// Default values
 	if voice == "" {
 		voice = defaultVoice
@@ -113,18 +213,31 @@ func NewCommunicate(text string, opts ...Option) (*Communicate, error) {
 	if pitch == "" {
 		pitch = "+0Hz"
 	}
+	if inputMode == "" {
+		inputMode = InputModeText
+	}
+	if codeBlocks == "" {
+		codeBlocks = CodeBlockSkip
+	}
+	if outputFormat == "" {
+		outputFormat = DefaultOutputFormat
+	}
 
 	// Validate voice
-	validVoicePattern := regexp.MustCompile(`^([a-z]{2,})-([A-Z]{2,})-(.+Neural)$`)
-	if validVoicePattern.MatchString(voice) {
-		voiceLangRegion = voice
-		strs := strings.Split(voice, "-")
-		lang := strs[0]
-		region := strs[1]
-		name := strs[2]
-		voice = fmt.Sprintf("Microsoft Server Speech Text to Speech Voice (%s-%s, %s)", lang, region, name)
-	} else {
-		return nil, errors.New("invalid voice")
+	voiceLangRegion = voice
+	voice, err := voiceName(voiceLangRegion)
+	if err != nil {
+		return nil, err
+	}
+
+	// Validate secondary voices, they read the runs of text in other languages
+	secondaryVoices := map[string]string{}
+	for _, v := range GetSecondaryVoicesByOption(opts) {
+		name, err := voiceName(v)
+		if err != nil {
+			return nil, err
+		}
+		secondaryVoices[voiceLocale(v)] = name
 	}
 
 	// Validate rate
@@ -139,6 +252,54 @@ func NewCommunicate(text string, opts ...Option) (*Communicate, error) {
 		return nil, errors.New("Invalid volume")
 	}
 
+	// Validate style, style degree and role against the voice catalog
+	if err := validateExpressAs(voiceLangRegion, style, styleDegree, role); err != nil {
+		return nil, err
+	}
+
+	// Validate output format
+	if !outputFormatPattern.MatchString(outputFormat) {
+		return nil, errors.New("invalid output format")
+	}
+
+	// Validate input mode
+	if inputMode != InputModeText && inputMode != InputModeMarkdown && inputMode != InputModeHTML {
+		return nil, errors.New("invalid input mode")
+	}
+	if codeBlocks != CodeBlockSkip && codeBlocks != CodeBlockAnnounce {
+		return nil, errors.New("invalid code block mode")
+	}
+
+	// Validate pauses
+	for _, pause := range []string{sentencePause, paragraphPause, chunkPause} {
+		if err := validatePause(pause); err != nil {
+			return nil, err
+		}
+	}
+	if len(pauseMarkers) > int(pauseLast-pauseMarker)+1 {
+		return nil, errors.New("too many pause markers")
+	}
+	for _, m := range pauseMarkers {
+		if m.Marker == "" {
+			return nil, errors.New("empty pause marker")
+		}
+		if err := validatePause(m.Duration); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := validateSanitizePolicy(sanitizePolicy); err != nil {
+		return nil, err
+	}
+
+	if err := validateLexicon(lexicon); err != nil {
+		return nil, err
+	}
+
+	if !hasNormalizers {
+		normalizers = NormalizersForLocale(voiceLocale(voiceLangRegion))
+	}
+
 	return &Communicate{
 		Text:            text,
 		Voice:           voice,
@@ -146,12 +307,31 @@ func NewCommunicate(text string, opts ...Option) (*Communicate, error) {
 		Rate:            rate,
 		Volume:          volume,
 		Pitch:           pitch,
+		Style:           style,
+		StyleDegree:     styleDegree,
+		Role:            role,
+		Normalizers:     normalizers,
+		InputMode:       inputMode,
+		CodeBlocks:      codeBlocks,
+		Lexicon:         lexicon,
+		SecondaryVoices: secondaryVoices,
+		Multilingual:    multilingual || isMultilingual(voiceLangRegion),
+		SentencePause:   sentencePause,
+		ParagraphPause:  paragraphPause,
+		ChunkPause:      chunkPause,
+		PauseMarkers:    pauseMarkers,
+		SanitizePolicy:  sanitizePolicy,
+		Progress:        progress,
+		OutputFormat:    outputFormat,
 		Proxy:           proxy,
 	}, nil
 }
 
+// CloseOutput stops the last Stream, its channel is closed once every connection is done.
 func (c *Communicate) CloseOutput() {
-	close(c.op)
+	if c.cancel != nil {
+		c.cancel()
+	}
 }
 
 func (c *Communicate) makeHeaders() http.Header {
@@ -165,26 +345,79 @@ func (c *Communicate) makeHeaders() http.Header {
 	return headers
 }
 
+// Stream is StreamContext for callers that stop it with CloseOutput, it records the number of
+// messages in AudioDataIndex, so a Communicate can only run one Stream at a time. Unlike StreamContext,
+// word boundary offsets run across the whole text: each message is shifted by the end of the last word
+// of the messages before it, and only word boundaries are sent.
 func (c *Communicate) Stream() (<-chan map[string]interface{}, error) {
-	texts := splitTextByByteLength(
-		escape(removeIncompatibleCharacters(c.Text)),
-		calcMaxMesgSize(c.Voice, c.Rate, c.Volume, c.Pitch),
-	)
-	c.AudioDataIndex = len(texts)
+	ctx, cancel := context.WithCancel(context.Background())
+	events, n, err := c.StreamContext(ctx)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	c.AudioDataIndex = n
+	c.cancel = cancel
+
+	output := make(chan map[string]interface{})
+	go func() {
+		defer close(output)
+		finalUtterance := make(map[int]int)
+		for event := range events {
+			// sentence boundaries are for the metadata and subtitles of Synthesize
+			if event["type"] == BoundarySentence {
+				continue
+			}
+			if event["type"] == BoundaryWord {
+				idx, _ := event["index"].(int)
+				offset, _ := event["offset"].(int)
+				duration, _ := event["duration"].(int)
+				finalUtterance[idx] = offset + duration + 8_750_000
+				event["offset"] = offset + sum(idx, finalUtterance)
+			}
+			select {
+			case output <- event:
+			case <-ctx.Done():
+			}
+		}
+	}()
+	return output, nil
+}
 
-	finalUtterance := make(map[int]int)
-	prevIdx := -1
-	shiftTime := -1
+func sum(idx int, m map[int]int) int {
+	sum := 0
+	for i := 0; i < idx; i++ {
+		sum += m[i]
+	}
+	return sum
+}
 
+// StreamContext sends every message of the text to the service and returns the channel of their
+// events with the number of messages. Audio and word boundary events carry the index of their
+// message, so do end events, boundary offsets are from the start of that message. The channel is closed when every
+// message has ended or ctx is done. StreamContext does not change c, many can run at once.
+func (c *Communicate) StreamContext(ctx context.Context) (<-chan map[string]interface{}, int, error) {
+	texts := c.splitText(c.prepareText())
+	progress := newProgressTracker(c.Progress, c.OutputFormat, len(texts))
+
+	ctx, cancel := context.WithCancel(ctx)
 	output := make(chan map[string]interface{})
+	send := func(event map[string]interface{}) {
+		select {
+		case output <- event:
+		case <-ctx.Done():
+		}
+	}
+	var wg sync.WaitGroup
 
 	for idx, text := range texts {
 		fmt.Printf("text=%s\n", text)
 		wsURL := WssURL + "&ConnectionId=" + connectID()
 		dialer := websocket.Dialer{}
-		conn, _, err := dialer.Dial(wsURL, c.makeHeaders())
+		conn, _, err := dialer.DialContext(ctx, wsURL, c.makeHeaders())
 		if err != nil {
-			return nil, err
+			cancel()
+			return nil, 0, err
 		}
 
 		// download indicates whether we should be expecting audio data,
@@ -214,27 +447,42 @@ func (c *Communicate) Stream() (<-chan map[string]interface{}, error) {
 			"X-Timestamp:"+date+"\r\n"+
 				"Content-Type:application/json; charset=utf-8\r\n"+
 				"Path:speech.config\r\n\r\n"+
-				`{"context":{"synthesis":{"audio":{"metadataoptions":{"sentenceBoundaryEnabled":false,"wordBoundaryEnabled":true},"outputFormat":"audio-24khz-48kbitrate-mono-mp3"}}}}`+"\r\n",
+				`{"context":{"synthesis":{"audio":{"metadataoptions":{"sentenceBoundaryEnabled":true,"wordBoundaryEnabled":true},"outputFormat":"`+c.OutputFormat+`"}}}}`+"\r\n",
 		))
 		if err != nil {
 			conn.Close()
-			return nil, err
+			cancel()
+			return nil, 0, err
 		}
 		connMsg := []byte(
 			ssmlHeadersPlusData(
 				connectID(),
 				date,
-				mkssml(string(text), c.Voice, c.Rate, c.Volume, c.Pitch),
+				c.mkssml(c.chunkText(texts, idx)),
 			),
 		)
 		err = conn.WriteMessage(websocket.TextMessage, connMsg)
 		if err != nil {
 			conn.Close()
-			return nil, err
+			cancel()
+			return nil, 0, err
 		}
+		progress.started()
 
+		wg.Add(1)
 		go func(idx int) {
+			defer wg.Done()
 			defer conn.Close()
+			// unblock the read below when ctx is done
+			done := make(chan struct{})
+			defer close(done)
+			go func() {
+				select {
+				case <-ctx.Done():
+					conn.Close()
+				case <-done:
+				}
+			}()
 			defer func() {
 				if err := recover(); err != nil {
 					fmt.Printf("Communicate.Stream recovered from panic: %v stack: %s", err, string(debug.Stack()))
@@ -246,9 +494,9 @@ func (c *Communicate) Stream() (<-chan map[string]interface{}, error) {
 				if err != nil {
 					if !websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
 						// WebSocket error
-						output <- map[string]interface{}{
+						send(map[string]interface{}{
 							"error": WebSocketError{Message: err.Error()},
-						}
+						})
 					}
 					break
 				}
@@ -259,9 +507,11 @@ func (c *Communicate) Stream() (<-chan map[string]interface{}, error) {
 					if path == "turn.start" {
 						downloadAudio = true
 					} else if path == "turn.end" {
-						output <- map[string]interface{}{
-							"end": "",
-						}
+						progress.finished()
+						send(map[string]interface{}{
+							"end":   "",
+							"index": idx,
+						})
 						downloadAudio = false
 						break // End of audio data
 					} else if path == "audio.metadata" {
@@ -269,121 +519,132 @@ func (c *Communicate) Stream() (<-chan map[string]interface{}, error) {
 							Metadata []struct {
 								Type string `json:"Type"`
 								Data struct {
-									Offset   int `json:"Offset"`
-									Duration int `json:"Duration"`
-									Text     struct {
-										Text         string `json:"Text"`
-										Length       int64  `json:"Length"`
-										BoundaryType string `json:"BoundaryType"`
-									} `json:"text"`
+									Offset   int          `json:"Offset"`
+									Duration int          `json:"Duration"`
+									Text     boundaryText `json:"text"`
 								} `json:"Data"`
 							} `json:"Metadata"`
 						}
 						err := sonic.Unmarshal(data, &metadata)
 						if err != nil {
 							msg := fmt.Sprintf("err=%s, data=%s", err.Error(), string(data))
-							output <- map[string]interface{}{
+							send(map[string]interface{}{
 								"error": UnknownResponse{Message: msg},
-							}
+							})
 							break
 						}
 
 						for _, metaObj := range metadata.Metadata {
 							metaType := metaObj.Type
-							if idx != prevIdx {
-								shiftTime = sum(idx, finalUtterance)
-								prevIdx = idx
-							}
-							if metaType == "WordBoundary" {
-								finalUtterance[idx] = metaObj.Data.Offset + metaObj.Data.Duration + 8_750_000
-								output <- map[string]interface{}{
+							if metaType == BoundaryWord || metaType == BoundarySentence {
+								send(map[string]interface{}{
 									"type":     metaType,
-									"offset":   metaObj.Data.Offset + shiftTime,
+									"index":    idx,
+									"offset":   metaObj.Data.Offset,
 									"duration": metaObj.Data.Duration,
 									"text":     metaObj.Data.Text,
-								}
+								})
 							} else if metaType == "SessionEnd" {
 								continue
 							} else {
-								output <- map[string]interface{}{
+								send(map[string]interface{}{
 									"error": UnknownResponse{Message: "Unknown metadata type: " + metaType},
-								}
+								})
 								break
 							}
 						}
 					} else if path == "response" {
 						// Do nothing
 					} else {
-						output <- map[string]interface{}{
+						send(map[string]interface{}{
 							"error": UnknownResponse{Message: "The response from the service is not recognized.\n" + string(message)},
-						}
+						})
 						break
 					}
 				} else if msgType == websocket.BinaryMessage {
 					if !downloadAudio {
-						output <- map[string]interface{}{
+						send(map[string]interface{}{
 							"error": UnknownResponse{"We received a binary message, but we are not expecting one."},
-						}
+						})
 					}
 
 					if len(message) < 2 {
-						output <- map[string]interface{}{
+						send(map[string]interface{}{
 							"error": UnknownResponse{"We received a binary message, but it is missing the header length."},
-						}
+						})
 					}
 
 					headerLength := int(binary.BigEndian.Uint16(message[:2]))
 					if len(message) < headerLength+2 {
-						output <- map[string]interface{}{
+						send(map[string]interface{}{
 							"error": UnknownResponse{"We received a binary message, but it is missing the audio data."},
-						}
+						})
 					}
 
 					audioData := message[headerLength+2:]
-					output <- map[string]interface{}{
+					progress.received(len(audioData))
+					send(map[string]interface{}{
 						"type": "audio",
 						"data": AudioData{
 							Data:  audioData,
 							Index: idx,
 						},
-					}
+					})
 					audioWasReceived = true
 				} else {
 					if message != nil {
-						output <- map[string]interface{}{
+						send(map[string]interface{}{
 							"error": WebSocketError{
 								Message: string(message),
 							},
-						}
+						})
 					} else {
-						output <- map[string]interface{}{
+						send(map[string]interface{}{
 							"error": WebSocketError{
 								Message: "Unknown error",
 							},
-						}
+						})
 					}
 				}
 
 			}
 
 			if !audioWasReceived {
-				output <- map[string]interface{}{
+				send(map[string]interface{}{
 					"error": NoAudioReceived{Message: "No audio was received. Please verify that your parameters are correct."},
-				}
+				})
 			}
 		}(idx)
 	}
-	c.op = output
-	return output, nil
+
+	go func() {
+		wg.Wait()
+		cancel()
+		close(output)
+	}()
+	return output, len(texts), nil
 }
 
-func sum(idx int, m map[int]int) int {
-	sum := 0
-	for i := 0; i < idx; i++ {
-		sum += m[i]
-	}
-	return sum
+// prepareText turns the input text into the escaped text that is split into ssml messages.
+func (c *Communicate) prepareText() string {
+	text, _ := Sanitize(c.Text, c.SanitizePolicy, voiceLocale(c.VoiceLangRegion))
+	text = c.Normalizers.Normalize(c.markPauses(c.toSpeakable(text)))
+	// control characters are left to the sanitize policy
+	text = escape(c.markSentences(text))
+	return applyLexicon(c.expandPauses(text), mergeLexicons(c.Lexicon, getGlobalLexicon()))
+}
 
+// CacheKey is a digest of everything that changes the audio: the output format and the ssml of every
+// message, which covers the voice, prosody, style, pauses, lexicon and text processing options.
+func (c *Communicate) CacheKey() string {
+	hash := sha256.New()
+	hash.Write([]byte(c.OutputFormat))
+	texts := c.splitText(c.prepareText())
+	for idx := range texts {
+		hash.Write([]byte{0})
+		hash.Write([]byte(c.mkssml(c.chunkText(texts, idx))))
+	}
+	return hex.EncodeToString(hash.Sum(nil))
 }
 
 func getHeadersAndData(data []byte) (map[string]string, []byte) {
@@ -407,19 +668,6 @@ func getHeadersAndData(data []byte) (map[string]string, []byte) {
 	return headers, data[headerEndIndex+4:]
 }
 
-func removeIncompatibleCharacters(str string) string {
-	chars := []rune(str)
-
-	for i, char := range chars {
-		code := int(char)
-		if (0 <= code && code <= 8) || (11 <= code && code <= 12) || (14 <= code && code <= 31) {
-			chars[i] = ' '
-		}
-	}
-
-	return string(chars)
-}
-
 func connectID() string {
 	return strings.ReplaceAll(uuid.New().String(), "-", "")
 }
@@ -455,6 +703,12 @@ func splitTextByByteLength(text string, byteLength int) [][]byte {
 			}
 		}
 
+		// Never split inside an ssml element such as <break time='500ms'/> or <sub alias='x'>y</sub>,
+		// literal brackets are escaped so any '<' here starts an element.
+		if start := openElementStart(textBytes[:splitAt]); start > 0 {
+			splitAt = start
+		}
+
 		trimmedText := bytes.TrimSpace(textBytes[:splitAt])
 		if len(trimmedText) > 0 {
 			result = append(result, trimmedText)
@@ -473,12 +727,64 @@ func splitTextByByteLength(text string, byteLength int) [][]byte {
 	return result
 }
 
-func mkssml(text string, voice string, rate string, volume string, pitch string) string {
-	ssml := fmt.Sprintf("<speak version='1.0' xmlns='http://www.w3.org/2001/10/synthesis' xml:lang='en-US'><voice name='%s'><prosody pitch='%s' rate='%s' volume='%s'>%s</prosody></voice></speak>",
-		voice, pitch, rate, volume, text)
+// openElementStart returns where the outermost ssml element that is still open at the end of data starts, or -1.
+func openElementStart(data []byte) int {
+	var open []int
+	for i := 0; i < len(data); i++ {
+		if data[i] != '<' {
+			continue
+		}
+		end := bytes.IndexByte(data[i:], '>')
+		if end == -1 {
+			open = append(open, i)
+			break
+		}
+		tag := data[i : i+end+1]
+		switch {
+		case bytes.HasPrefix(tag, []byte("</")):
+			if len(open) > 0 {
+				open = open[:len(open)-1]
+			}
+		case !bytes.HasSuffix(tag, []byte("/>")):
+			open = append(open, i)
+		}
+		i += end
+	}
+	if len(open) == 0 {
+		return -1
+	}
+	return open[0]
+}
+
+func (c *Communicate) mkssml(text string) string {
+	var body strings.Builder
+	for _, seg := range c.segments(text) {
+		content := fmt.Sprintf("<prosody pitch='%s' rate='%s' volume='%s'>%s</prosody>", c.Pitch, c.Rate, c.Volume, seg.text)
+		if seg.voice == c.Voice {
+			content = c.expressAs(content)
+		}
+		fmt.Fprintf(&body, "<voice name='%s'>%s</voice>", seg.voice, content)
+	}
+	ssml := fmt.Sprintf("<speak version='1.0' xmlns='http://www.w3.org/2001/10/synthesis' xmlns:mstts='https://www.w3.org/2001/mstts' xml:lang='%s'>%s</speak>",
+		voiceLocale(c.VoiceLangRegion), body.String())
 	return ssml
 }
 
+// expressAs wraps content in mstts:express-as when a style is configured.
+func (c *Communicate) expressAs(content string) string {
+	if c.Style == "" {
+		return content
+	}
+	attrs := fmt.Sprintf("style='%s'", c.Style)
+	if c.StyleDegree != "" {
+		attrs += fmt.Sprintf(" styledegree='%s'", c.StyleDegree)
+	}
+	if c.Role != "" {
+		attrs += fmt.Sprintf(" role='%s'", c.Role)
+	}
+	return fmt.Sprintf("<mstts:express-as %s>%s</mstts:express-as>", attrs, content)
+}
+
 func dateToString() string {
 	// Use time.FixedZone to represent a fixed timezone offset of 0 (UTC)
 	zone := time.FixedZone("UTC", 0)
@@ -492,12 +798,36 @@ func ssmlHeadersPlusData(requestID string, timestamp string, ssml string) string
 	return headers + ssml
 }
 
-func calcMaxMesgSize(voice string, rate string, volume string, pitch string) int {
-	websocketMaxSize := 1 << 16
-	overheadPerMessage := len(ssmlHeadersPlusData(connectID(), dateToString(), mkssml("", voice, rate, volume, pitch))) + 50
+const websocketMaxSize = 1 << 16
+
+func (c *Communicate) calcMaxMesgSize() int {
+	overheadPerMessage := len(ssmlHeadersPlusData(connectID(), dateToString(), c.mkssml(""))) + 50
 	return websocketMaxSize - overheadPerMessage
 }
 
+// splitText splits the prepared text by calcMaxMesgSize, then splits again every chunk whose ssml
+// still does not fit in a message, which happens when the text switches language many times.
+func (c *Communicate) splitText(text string) [][]byte {
+	byteLength := c.calcMaxMesgSize()
+	var result [][]byte
+	for _, chunk := range splitTextByByteLength(text, byteLength) {
+		result = append(result, c.refitChunk(chunk, byteLength)...)
+	}
+	return result
+}
+
+func (c *Communicate) refitChunk(chunk []byte, byteLength int) [][]byte {
+	size := len(ssmlHeadersPlusData(connectID(), dateToString(), c.mkssml(string(chunk)))) + 50
+	if size <= websocketMaxSize || byteLength <= 1024 {
+		return [][]byte{chunk}
+	}
+	var result [][]byte
+	for _, sub := range splitTextByByteLength(string(chunk), byteLength/2) {
+		result = append(result, c.refitChunk(sub, byteLength/2)...)
+	}
+	return result
+}
+
 func escape(data string) string {
 	// Must do ampersand first
 	entities := make(map[string]string)
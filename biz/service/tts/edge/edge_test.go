@@ -0,0 +1,242 @@
+package edge
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMkssmlExpressAs(t *testing.T) {
+	c, err := NewCommunicate("hello", WithVoice("zh-CN-XiaomoNeural"), WithStyle("cheerful"), WithStyleDegree("1.5"), WithRole("Girl"))
+	if err != nil {
+		t.Fatalf("NewCommunicate fail, err: %v", err)
+	}
+	ssml := c.mkssml("hello")
+	if !strings.Contains(ssml, "xmlns:mstts='https://www.w3.org/2001/mstts'") {
+		t.Errorf("mstts namespace missing, ssml: %s", ssml)
+	}
+	if !strings.Contains(ssml, "<mstts:express-as style='cheerful' styledegree='1.5' role='Girl'><prosody") {
+		t.Errorf("express-as missing, ssml: %s", ssml)
+	}
+}
+
+func TestNewCommunicateInvalidStyle(t *testing.T) {
+	cases := [][]Option{
+		{WithVoice("zh-CN-XiaoxiaoNeural"), WithStyle("shouting")},
+		{WithVoice("zh-CN-XiaoxiaoNeural"), WithStyle("cheerful"), WithRole("Girl")},
+		{WithVoice("zh-CN-XiaoxiaoNeural"), WithStyle("cheerful"), WithStyleDegree("3")},
+		{WithVoice("zh-CN-XiaoxiaoNeural"), WithRole("Girl")},
+	}
+	for _, opts := range cases {
+		if _, err := NewCommunicate("hello", opts...); err == nil {
+			t.Errorf("NewCommunicate should fail, opts: %v", opts)
+		}
+	}
+}
+
+func TestPrepareTextMarkup(t *testing.T) {
+	md, err := NewCommunicate("# Title\n\nSee **the** [docs](https://example.com).\n\n```go\nfmt.Println()\n```\n- one\n- two",
+		WithVoice("en-US-AriaNeural"), WithInputMode(InputModeMarkdown), WithCodeBlocks(CodeBlockAnnounce), WithNormalizers())
+	if err != nil {
+		t.Fatalf("NewCommunicate fail, err: %v", err)
+	}
+	want := "Title<break time='700ms'/> See the docs.<break time='500ms'/> code block<break time='500ms'/> one<break time='300ms'/> two"
+	if got := md.prepareText(); got != want {
+		t.Errorf("markdown prepareText = %q, want %q", got, want)
+	}
+
+	h, err := NewCommunicate("<h1>Title</h1><p>Fish &amp; chips</p><script>x()</script><ul><li>one</li></ul>",
+		WithVoice("en-US-AriaNeural"), WithInputMode(InputModeHTML), WithNormalizers())
+	if err != nil {
+		t.Fatalf("NewCommunicate fail, err: %v", err)
+	}
+	want = "Title<break time='700ms'/> Fish &amp; chips<break time='500ms'/> one"
+	if got := h.prepareText(); got != want {
+		t.Errorf("html prepareText = %q, want %q", got, want)
+	}
+}
+
+func TestSplitTextKeepsElements(t *testing.T) {
+	text := "aaaa <break time='500ms'/> bbbb"
+	for _, chunk := range splitTextByByteLength(text, 24) {
+		if strings.Count(string(chunk), "<") != strings.Count(string(chunk), ">") {
+			t.Errorf("element split across chunks: %q", chunk)
+		}
+	}
+}
+
+func TestLexicon(t *testing.T) {
+	tsv := "# brand names\nSQL\tsequel\n重庆\tchong 2 qing 4\tsapi\n"
+	lexicon, err := LoadLexiconTSV(strings.NewReader(tsv))
+	if err != nil {
+		t.Fatalf("LoadLexiconTSV fail, err: %v", err)
+	}
+	c, err := NewCommunicate("SQL & SQLite in 重庆", WithLexicon(lexicon), WithNormalizers())
+	if err != nil {
+		t.Fatalf("NewCommunicate fail, err: %v", err)
+	}
+	want := "<sub alias='sequel'>SQL</sub> &amp; SQLite in <phoneme alphabet='sapi' ph='chong 2 qing 4'>重庆</phoneme>"
+	if got := c.prepareText(); got != want {
+		t.Errorf("prepareText = %q, want %q", got, want)
+	}
+
+	pls := `<?xml version="1.0" encoding="UTF-8"?>
+<lexicon version="1.0" xmlns="http://www.w3.org/2005/01/pronunciation-lexicon" alphabet="ipa" xml:lang="en-US">
+  <lexeme><grapheme>tomato</grapheme><phoneme>təˈmɑːtoʊ</phoneme></lexeme>
+  <lexeme><grapheme>BTW</grapheme><alias>by the way</alias></lexeme>
+</lexicon>`
+	lexicon, err = LoadLexiconPLS(strings.NewReader(pls))
+	if err != nil {
+		t.Fatalf("LoadLexiconPLS fail, err: %v", err)
+	}
+	if entries := lexicon.Entries(); len(entries) != 2 || entries[0].Alphabet != AlphabetIPA || entries[1].Alias != "by the way" {
+		t.Errorf("unexpected pls entries: %+v", entries)
+	}
+}
+
+func TestMkssmlLanguages(t *testing.T) {
+	c, err := NewCommunicate("我在用iPhone打电话", WithSecondaryVoice("en-US-GuyNeural"), WithNormalizers())
+	if err != nil {
+		t.Fatalf("NewCommunicate fail, err: %v", err)
+	}
+	ssml := c.mkssml(c.prepareText())
+	if !strings.Contains(ssml, "xml:lang='zh-CN'") {
+		t.Errorf("speak should use the voice locale, ssml: %s", ssml)
+	}
+	if !strings.Contains(ssml, "<voice name='Microsoft Server Speech Text to Speech Voice (en-US, GuyNeural)'><prosody pitch='+0Hz' rate='+0%' volume='+0%'>iPhone</prosody></voice>") {
+		t.Errorf("english run should use the secondary voice, ssml: %s", ssml)
+	}
+
+	c, err = NewCommunicate("我在用iPhone打电话", WithVoice("en-US-JennyMultilingualNeural"), WithNormalizers())
+	if err != nil {
+		t.Fatalf("NewCommunicate fail, err: %v", err)
+	}
+	ssml = c.mkssml(c.prepareText())
+	if !strings.Contains(ssml, "<lang xml:lang='zh-CN'>我在用</lang>iPhone<lang xml:lang='zh-CN'>打电话</lang>") {
+		t.Errorf("multilingual voice should switch with lang, ssml: %s", ssml)
+	}
+
+	// secondary voices of one script are picked in a stable order
+	for i := 0; i < 10; i++ {
+		c, err = NewCommunicate("我在用iPhone打电话", WithSecondaryVoice("fr-FR-DeniseNeural"), WithSecondaryVoice("en-US-GuyNeural"), WithNormalizers())
+		if err != nil {
+			t.Fatalf("NewCommunicate fail, err: %v", err)
+		}
+		if ssml = c.mkssml(c.prepareText()); !strings.Contains(ssml, "(en-US, GuyNeural)'><prosody pitch='+0Hz' rate='+0%' volume='+0%'>iPhone") {
+			t.Fatalf("latin run should use the first locale in order, ssml: %s", ssml)
+		}
+	}
+}
+
+func TestPauses(t *testing.T) {
+	c, err := NewCommunicate("Hello world. How are you?\n\nFine [pause] thanks.",
+		WithVoice("en-US-AriaNeural"), WithSentencePause("300ms"), WithParagraphPause("1s"), WithPauseMarker("[pause]", "2s"), WithNormalizers())
+	if err != nil {
+		t.Fatalf("NewCommunicate fail, err: %v", err)
+	}
+	want := "Hello world.<break time='300ms'/> How are you?<break time='1s'/> Fine <break time='2s'/> thanks."
+	if got := c.prepareText(); got != want {
+		t.Errorf("prepareText = %q, want %q", got, want)
+	}
+
+	texts := [][]byte{[]byte("one"), []byte("two<break time='1s'/>"), []byte("three")}
+	if got := c.chunkText(texts, 0); got != "one<break time='300ms'/>" {
+		t.Errorf("chunk pause missing, got %q", got)
+	}
+	if got := c.chunkText(texts, 1); got != string(texts[1]) {
+		t.Errorf("chunk ending with a break should be kept, got %q", got)
+	}
+	if got := c.chunkText(texts, 2); got != "three" {
+		t.Errorf("last chunk should not pause, got %q", got)
+	}
+
+	if _, err := NewCommunicate("hello", WithSentencePause("long")); err == nil {
+		t.Errorf("invalid pause should fail")
+	}
+}
+
+func TestSanitize(t *testing.T) {
+	policy := SanitizePolicy{Emoji: SanitizeReplace, ZeroWidth: SanitizeStrip, Symbols: SanitizeStrip}
+	c, err := NewCommunicate("好\a棒👍🏻\u200b了★🇨🇳", WithSanitizePolicy(policy), WithNormalizers())
+	if err != nil {
+		t.Fatalf("NewCommunicate fail, err: %v", err)
+	}
+	if got := c.prepareText(); got != "好 棒 点赞 了" {
+		t.Errorf("prepareText = %q", got)
+	}
+	changes := c.SanitizeReport()
+	want := []SanitizeChange{
+		{Offset: 3, Length: 1, Original: "\a", Replacement: " ", Category: SanitizeCategoryControl},
+		{Offset: 7, Length: 8, Original: "👍🏻", Replacement: " 点赞 ", Category: SanitizeCategoryEmoji},
+		{Offset: 15, Length: 3, Original: "\u200b", Replacement: "", Category: SanitizeCategoryZeroWidth},
+		{Offset: 21, Length: 3, Original: "★", Replacement: "", Category: SanitizeCategorySymbol},
+		{Offset: 24, Length: 8, Original: "🇨🇳", Replacement: "", Category: SanitizeCategoryEmoji},
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("SanitizeReport = %+v", changes)
+	}
+	for i := range want {
+		if changes[i] != want[i] {
+			t.Errorf("change %d = %+v, want %+v", i, changes[i], want[i])
+		}
+	}
+}
+
+func TestSanitizeDefault(t *testing.T) {
+	// the default policy replaces the control characters the service has always received as spaces, no more
+	text := "a\tb\u0007c\u007fd\u0085e"
+	if got, _ := Sanitize(text, DefaultSanitizePolicy(), "en"); got != "a\tb c\u007fd\u0085e" {
+		t.Errorf("Sanitize = %q", got)
+	}
+
+	// the private use characters of the pause markers can not be given in the input
+	c, err := NewCommunicate("a\uE002b\uE010c", WithVoice("en-US-AriaNeural"), WithSentencePause("300ms"), WithNormalizers())
+	if err != nil {
+		t.Fatalf("NewCommunicate fail, err: %v", err)
+	}
+	if got := c.prepareText(); got != "abc" {
+		t.Errorf("prepareText = %q", got)
+	}
+	if changes := c.SanitizeReport(); len(changes) != 2 || changes[1] != (SanitizeChange{Offset: 5, Length: 3, Original: "\uE010", Category: SanitizeCategoryReserved}) {
+		t.Errorf("SanitizeReport = %+v", changes)
+	}
+
+	// kept control characters reach the service
+	c, err = NewCommunicate("a\u0007b", WithSanitizePolicy(SanitizePolicy{Control: SanitizeKeep}), WithNormalizers())
+	if err != nil {
+		t.Fatalf("NewCommunicate fail, err: %v", err)
+	}
+	if got := c.prepareText(); got != "a\u0007b" || len(c.SanitizeReport()) != 0 {
+		t.Errorf("prepareText = %q, report %+v", got, c.SanitizeReport())
+	}
+}
+
+func TestCacheKey(t *testing.T) {
+	key := func(opts ...Option) string {
+		c, err := NewCommunicate("Hello. Bye.", append([]Option{WithVoice("zh-CN-XiaomoNeural")}, opts...)...)
+		if err != nil {
+			t.Fatalf("NewCommunicate fail, err: %v", err)
+		}
+		return c.CacheKey()
+	}
+	base := key()
+	if base != key() {
+		t.Errorf("cache key should be stable")
+	}
+	for _, opt := range []Option{WithStyle("cheerful"), WithOutputFormat("riff-24khz-16bit-mono-pcm"), WithSentencePause("1s"), WithRate("+10%")} {
+		if key(opt) == base {
+			t.Errorf("option %v should change the cache key", opt)
+		}
+	}
+	if got := FileExtension("riff-24khz-16bit-mono-pcm"); got != "wav" {
+		t.Errorf("FileExtension = %s", got)
+	}
+	if got := AudioSeconds(DefaultOutputFormat, 6000); got != 1 {
+		t.Errorf("AudioSeconds = %v", got)
+	}
+	if rate, bitrate := FormatSampleRate("riff-44100hz-16bit-mono-pcm"), FormatBitrate("riff-44100hz-16bit-mono-pcm"); rate != 44100 || bitrate != 705600 {
+		t.Errorf("FormatSampleRate = %d, FormatBitrate = %d", rate, bitrate)
+	}
+	if rate := FormatSampleRate("amr-wb-16000hz"); rate != 16000 {
+		t.Errorf("FormatSampleRate = %d", rate)
+	}
+}
@@ -0,0 +1,84 @@
+package edge
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// DefaultOutputFormat is the audio format asked from the service unless WithOutputFormat is given.
+// https://learn.microsoft.com/en-us/azure/cognitive-services/speech-service/rest-text-to-speech#audio-outputs
+const DefaultOutputFormat = "audio-24khz-48kbitrate-mono-mp3"
+
+var (
+	outputFormatPattern     = regexp.MustCompile(`^(audio|riff|raw|ogg|webm|amr)-[0-9a-z-]+$`)
+	formatBitratePattern    = regexp.MustCompile(`-(\d+)kbitrate-`)
+	formatPCMPattern        = regexp.MustCompile(`-(\d+)(k?)hz-(\d+)bit-`)
+	formatSampleRatePattern = regexp.MustCompile(`-(\d+)(k?)hz(-|$)`)
+)
+
+// FileExtension returns the file extension of an output format, without the dot.
+func FileExtension(format string) string {
+	switch {
+	case strings.HasSuffix(format, "-mp3"):
+		return "mp3"
+	case strings.HasPrefix(format, "riff-"):
+		return "wav"
+	case strings.HasPrefix(format, "ogg-"):
+		return "ogg"
+	case strings.HasPrefix(format, "webm-"):
+		return "webm"
+	case strings.HasPrefix(format, "amr-"):
+		return "amr"
+	default:
+		return "pcm"
+	}
+}
+
+// FormatBitrate returns the bits per second of an output format, or 0 when it can not be told from its name.
+func FormatBitrate(format string) int {
+	if m := formatBitratePattern.FindStringSubmatch(format); m != nil {
+		kbit, _ := strconv.Atoi(m[1])
+		return kbit * 1000
+	}
+	if m := formatPCMPattern.FindStringSubmatch(format); m != nil {
+		bit, _ := strconv.Atoi(m[3])
+		return FormatSampleRate(format) * bit * FormatChannels(format)
+	}
+	return 0
+}
+
+// FormatSampleRate returns the samples per second of an output format, or 0 when it can not be told from its name.
+func FormatSampleRate(format string) int {
+	m := formatSampleRatePattern.FindStringSubmatch(format)
+	if m == nil {
+		return 0
+	}
+	rate, _ := strconv.Atoi(m[1])
+	if m[2] == "k" {
+		rate *= 1000
+	}
+	return rate
+}
+
+// FormatChannels returns the channels of an output format, the voices of the service are mono.
+func FormatChannels(format string) int {
+	if strings.Contains(format, "-stereo-") {
+		return 2
+	}
+	return 1
+}
+
+// AudioSeconds estimates the duration of size bytes of audio in format.
+func AudioSeconds(format string, size int64) float64 {
+	bitrate := FormatBitrate(format)
+	if bitrate == 0 {
+		return 0
+	}
+	return float64(size) * 8 / float64(bitrate)
+}
+
+// FileExtension returns the file extension of the output format of c.
+func (c *Communicate) FileExtension() string {
+	return FileExtension(c.OutputFormat)
+}
@@ -0,0 +1,265 @@
+package edge
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ssmlSegment is a run of text read by one voice.
+type ssmlSegment struct {
+	voice string
+	text  string
+}
+
+// languageRun is a run of text in one language.
+type languageRun struct {
+	locale string
+	text   string
+}
+
+var scripts = []struct {
+	name  string
+	table *unicode.RangeTable
+}{
+	{"Han", unicode.Han},
+	{"Kana", unicode.Hiragana},
+	{"Kana", unicode.Katakana},
+	{"Hangul", unicode.Hangul},
+	{"Cyrillic", unicode.Cyrillic},
+	{"Arabic", unicode.Arabic},
+	{"Thai", unicode.Thai},
+	{"Greek", unicode.Greek},
+	{"Hebrew", unicode.Hebrew},
+	{"Devanagari", unicode.Devanagari},
+	{"Latin", unicode.Latin},
+}
+
+// scriptLocales is the locale assumed for a script that is neither the voice's nor a secondary voice's.
+var scriptLocales = map[string]string{
+	"Han":        "zh-CN",
+	"Kana":       "ja-JP",
+	"Hangul":     "ko-KR",
+	"Cyrillic":   "ru-RU",
+	"Arabic":     "ar-SA",
+	"Thai":       "th-TH",
+	"Greek":      "el-GR",
+	"Hebrew":     "he-IL",
+	"Devanagari": "hi-IN",
+	"Latin":      "en-US",
+}
+
+// languageScripts lists the scripts a language is written in, latin is the default.
+var languageScripts = map[string][]string{
+	"zh": {"Han"},
+	"ja": {"Kana", "Han"},
+	"ko": {"Hangul"},
+	"ru": {"Cyrillic"},
+	"uk": {"Cyrillic"},
+	"bg": {"Cyrillic"},
+	"ar": {"Arabic"},
+	"fa": {"Arabic"},
+	"th": {"Thai"},
+	"el": {"Greek"},
+	"he": {"Hebrew"},
+	"hi": {"Devanagari"},
+}
+
+// scriptOf returns the script of a letter, or "" for digits, punctuation and spaces which join any run.
+func scriptOf(r rune) string {
+	if !unicode.IsLetter(r) {
+		return ""
+	}
+	for _, s := range scripts {
+		if unicode.Is(s.table, r) {
+			return s.name
+		}
+	}
+	return ""
+}
+
+func localeWritesScript(locale, script string) bool {
+	lang := strings.SplitN(locale, "-", 2)[0]
+	written, ok := languageScripts[lang]
+	if !ok {
+		written = []string{"Latin"}
+	}
+	for _, s := range written {
+		if s == script {
+			return true
+		}
+	}
+	return false
+}
+
+// scriptLocale picks the locale of a run written in script. When several secondary voices write the
+// script, the first locale in sorted order reads it, so the ssml and the cache key do not change between runs.
+func (c *Communicate) scriptLocale(script string) string {
+	locale := voiceLocale(c.VoiceLangRegion)
+	if script == "" || localeWritesScript(locale, script) {
+		return locale
+	}
+	locales := make([]string, 0, len(c.SecondaryVoices))
+	for l := range c.SecondaryVoices {
+		locales = append(locales, l)
+	}
+	sort.Strings(locales)
+	for _, l := range locales {
+		if localeWritesScript(l, script) {
+			return l
+		}
+	}
+	return scriptLocales[script]
+}
+
+// languageRuns splits escaped text into runs by the script of their letters. ssml elements are
+// kept whole and belong to the script of their content, entities and elements without content
+// join the surrounding run.
+func (c *Communicate) languageRuns(text string) []languageRun {
+	var runs []languageRun
+	var neutral strings.Builder
+	add := func(locale, s string) {
+		if locale == "" {
+			if len(runs) == 0 {
+				neutral.WriteString(s)
+			} else {
+				runs[len(runs)-1].text += s
+			}
+			return
+		}
+		if len(runs) > 0 && runs[len(runs)-1].locale == locale {
+			runs[len(runs)-1].text += s
+			return
+		}
+		if len(runs) == 0 {
+			s = neutral.String() + s
+		}
+		runs = append(runs, languageRun{locale: locale, text: s})
+	}
+
+	for i := 0; i < len(text); {
+		switch text[i] {
+		case '<':
+			end := elementEnd(text, i)
+			element := text[i:end]
+			locale := ""
+			if script := firstScript(stripElements(element)); script != "" {
+				locale = c.scriptLocale(script)
+			}
+			add(locale, element)
+			i = end
+		case '&':
+			end := strings.IndexByte(text[i:], ';')
+			if end == -1 {
+				end = len(text) - i - 1
+			}
+			add("", text[i:i+end+1])
+			i += end + 1
+		default:
+			r, size := utf8.DecodeRuneInString(text[i:])
+			locale := ""
+			if script := scriptOf(r); script != "" {
+				locale = c.scriptLocale(script)
+			}
+			add(locale, text[i:i+size])
+			i += size
+		}
+	}
+	if len(runs) == 0 && neutral.Len() > 0 {
+		runs = append(runs, languageRun{locale: voiceLocale(c.VoiceLangRegion), text: neutral.String()})
+	}
+	return runs
+}
+
+// elementEnd returns the index right after the element starting at text[start].
+func elementEnd(text string, start int) int {
+	depth := 0
+	for i := start; i < len(text); i++ {
+		if text[i] != '<' {
+			continue
+		}
+		end := strings.IndexByte(text[i:], '>')
+		if end == -1 {
+			return len(text)
+		}
+		tag := text[i : i+end+1]
+		switch {
+		case strings.HasPrefix(tag, "</"):
+			depth--
+		case !strings.HasSuffix(tag, "/>"):
+			depth++
+		}
+		i += end
+		if depth <= 0 {
+			return i + 1
+		}
+	}
+	return len(text)
+}
+
+func stripElements(text string) string {
+	var b strings.Builder
+	inTag := false
+	for _, r := range text {
+		switch {
+		case r == '<':
+			inTag = true
+		case r == '>':
+			inTag = false
+		case !inTag:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func firstScript(text string) string {
+	for _, r := range text {
+		if script := scriptOf(r); script != "" {
+			return script
+		}
+	}
+	return ""
+}
+
+// segments splits a chunk of escaped text into the voices that read it. A multilingual voice reads
+// everything and switches language with <lang>, otherwise runs in a language that has a secondary
+// voice are read by that voice.
+func (c *Communicate) segments(text string) []ssmlSegment {
+	if !c.Multilingual && len(c.SecondaryVoices) == 0 {
+		return []ssmlSegment{{voice: c.Voice, text: text}}
+	}
+
+	locale := voiceLocale(c.VoiceLangRegion)
+	runs := c.languageRuns(text)
+	if c.Multilingual {
+		var b strings.Builder
+		for _, run := range runs {
+			if run.locale == locale {
+				b.WriteString(run.text)
+			} else {
+				fmt.Fprintf(&b, "<lang xml:lang='%s'>%s</lang>", run.locale, run.text)
+			}
+		}
+		return []ssmlSegment{{voice: c.Voice, text: b.String()}}
+	}
+
+	var segs []ssmlSegment
+	for _, run := range runs {
+		voice := c.Voice
+		if v, ok := c.SecondaryVoices[run.locale]; ok && run.locale != locale {
+			voice = v
+		}
+		if len(segs) > 0 && segs[len(segs)-1].voice == voice {
+			segs[len(segs)-1].text += run.text
+		} else {
+			segs = append(segs, ssmlSegment{voice: voice, text: run.text})
+		}
+	}
+	if len(segs) == 0 {
+		segs = append(segs, ssmlSegment{voice: c.Voice, text: text})
+	}
+	return segs
+}
@@ -0,0 +1,262 @@
+package edge
+
+import (
+	"bufio"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	// AlphabetIPA is the International Phonetic Alphabet
+	AlphabetIPA = "ipa"
+	// AlphabetSAPI is the Microsoft SAPI phone set, for zh-CN it is pinyin with tones, e.g. chong 2 qing 4
+	AlphabetSAPI = "sapi"
+	// AlphabetUPS is the Microsoft Universal Phone Set
+	AlphabetUPS = "ups"
+)
+
+// LexiconEntry maps a word or phrase to either an alias or a phoneme.
+type LexiconEntry struct {
+	Grapheme string
+	// Alias is read instead of the grapheme, rendered as <sub alias>
+	Alias string
+	// Phoneme is the pronunciation in Alphabet, rendered as <phoneme>
+	Phoneme  string
+	Alphabet string
+}
+
+// Lexicon is a set of custom pronunciations applied while the ssml is built.
+type Lexicon struct {
+	mu      sync.RWMutex
+	entries map[string]LexiconEntry
+}
+
+func NewLexicon(entries ...LexiconEntry) *Lexicon {
+	l := &Lexicon{entries: map[string]LexiconEntry{}}
+	for _, e := range entries {
+		l.Add(e)
+	}
+	return l
+}
+
+// Add adds or replaces the entry of a grapheme.
+func (l *Lexicon) Add(entry LexiconEntry) {
+	if entry.Grapheme == "" {
+		return
+	}
+	if entry.Phoneme != "" && entry.Alphabet == "" {
+		entry.Alphabet = AlphabetIPA
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries[entry.Grapheme] = entry
+}
+
+// Entries returns the entries sorted by grapheme, longest first.
+func (l *Lexicon) Entries() []LexiconEntry {
+	if l == nil {
+		return nil
+	}
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	entries := make([]LexiconEntry, 0, len(l.entries))
+	for _, e := range l.entries {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if len(entries[i].Grapheme) != len(entries[j].Grapheme) {
+			return len(entries[i].Grapheme) > len(entries[j].Grapheme)
+		}
+		return entries[i].Grapheme < entries[j].Grapheme
+	})
+	return entries
+}
+
+var (
+	globalLexiconMu sync.RWMutex
+	globalLexicon   *Lexicon
+)
+
+// SetGlobalLexicon sets the lexicon applied to every Communicate, entries of a Communicate lexicon take precedence.
+func SetGlobalLexicon(l *Lexicon) {
+	globalLexiconMu.Lock()
+	defer globalLexiconMu.Unlock()
+	globalLexicon = l
+}
+
+func getGlobalLexicon() *Lexicon {
+	globalLexiconMu.RLock()
+	defer globalLexiconMu.RUnlock()
+	return globalLexicon
+}
+
+// LoadLexiconPLS reads a W3C Pronunciation Lexicon Specification document.
+// https://www.w3.org/TR/pronunciation-lexicon/
+func LoadLexiconPLS(r io.Reader) (*Lexicon, error) {
+	var doc struct {
+		Alphabet string `xml:"alphabet,attr"`
+		Lexemes  []struct {
+			Graphemes []string `xml:"grapheme"`
+			Phonemes  []struct {
+				Value    string `xml:",chardata"`
+				Alphabet string `xml:"alphabet,attr"`
+			} `xml:"phoneme"`
+			Aliases []string `xml:"alias"`
+		} `xml:"lexeme"`
+	}
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("decode pls lexicon err. %s", err.Error())
+	}
+
+	l := NewLexicon()
+	for _, lexeme := range doc.Lexemes {
+		entry := LexiconEntry{}
+		if len(lexeme.Aliases) > 0 {
+			entry.Alias = strings.TrimSpace(lexeme.Aliases[0])
+		} else if len(lexeme.Phonemes) > 0 {
+			entry.Phoneme = strings.TrimSpace(lexeme.Phonemes[0].Value)
+			entry.Alphabet = lexeme.Phonemes[0].Alphabet
+			if entry.Alphabet == "" {
+				entry.Alphabet = doc.Alphabet
+			}
+		} else {
+			continue
+		}
+		for _, g := range lexeme.Graphemes {
+			entry.Grapheme = strings.TrimSpace(g)
+			l.Add(entry)
+		}
+	}
+	return l, nil
+}
+
+// LoadLexiconTSV reads a tab separated lexicon, one entry per line:
+//
+//	grapheme<TAB>alias
+//	grapheme<TAB>phoneme<TAB>alphabet
+//
+// Empty lines and lines starting with # are ignored.
+func LoadLexiconTSV(r io.Reader) (*Lexicon, error) {
+	l := NewLexicon()
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		switch len(fields) {
+		case 2:
+			l.Add(LexiconEntry{Grapheme: fields[0], Alias: fields[1]})
+		case 3:
+			l.Add(LexiconEntry{Grapheme: fields[0], Phoneme: fields[1], Alphabet: fields[2]})
+		default:
+			return nil, fmt.Errorf("invalid lexicon line %d: %q", lineNo, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// mergeLexicons returns the entries of all lexicons, earlier lexicons take precedence.
+func mergeLexicons(lexicons ...*Lexicon) []LexiconEntry {
+	seen := map[string]bool{}
+	var entries []LexiconEntry
+	for _, l := range lexicons {
+		for _, e := range l.Entries() {
+			if !seen[e.Grapheme] {
+				seen[e.Grapheme] = true
+				entries = append(entries, e)
+			}
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return len(entries[i].Grapheme) > len(entries[j].Grapheme)
+	})
+	return entries
+}
+
+// applyLexicon wraps the graphemes found in escaped text with <sub> or <phoneme>,
+// matching the longest grapheme first and never inside an existing element or entity.
+func applyLexicon(text string, entries []LexiconEntry) string {
+	if len(entries) == 0 {
+		return text
+	}
+
+	escaped := make([]string, len(entries))
+	for i, e := range entries {
+		escaped[i] = escape(e.Grapheme)
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(text); {
+		if text[i] == '<' || text[i] == '&' {
+			closer := byte('>')
+			if text[i] == '&' {
+				closer = ';'
+			}
+			end := strings.IndexByte(text[i:], closer)
+			if end == -1 {
+				b.WriteString(text[i:])
+				break
+			}
+			b.WriteString(text[i : i+end+1])
+			i += end + 1
+			continue
+		}
+		matched := false
+		for k, g := range escaped {
+			if strings.HasPrefix(text[i:], g) && isWordEdge(text, i, i+len(g)) {
+				b.WriteString(lexiconElement(entries[k], g))
+				i += len(g)
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			b.WriteByte(text[i])
+			i++
+		}
+	}
+	return b.String()
+}
+
+// isWordEdge reports whether text[start:end] is not part of a longer latin word,
+// CJK text has no spaces so it always matches.
+func isWordEdge(text string, start, end int) bool {
+	isLatin := func(c byte) bool {
+		return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+	}
+	if start > 0 && isLatin(text[start-1]) && isLatin(text[start]) {
+		return false
+	}
+	if end < len(text) && isLatin(text[end]) && isLatin(text[end-1]) {
+		return false
+	}
+	return true
+}
+
+func lexiconElement(e LexiconEntry, grapheme string) string {
+	if e.Alias != "" {
+		return fmt.Sprintf("<sub alias='%s'>%s</sub>", escape(e.Alias), grapheme)
+	}
+	return fmt.Sprintf("<phoneme alphabet='%s' ph='%s'>%s</phoneme>", escape(e.Alphabet), escape(e.Phoneme), grapheme)
+}
+
+func validateLexicon(l *Lexicon) error {
+	for _, e := range l.Entries() {
+		if e.Alias == "" && e.Phoneme == "" {
+			return errors.New("lexicon entry " + e.Grapheme + " has neither alias nor phoneme")
+		}
+	}
+	return nil
+}
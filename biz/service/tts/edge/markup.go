@@ -0,0 +1,186 @@
+package edge
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+const (
+	// InputModeText reads the input as it is
+	InputModeText = "text"
+	// InputModeMarkdown strips markdown syntax and pauses after headings, paragraphs and list items
+	InputModeMarkdown = "markdown"
+	// InputModeHTML strips html tags and pauses after headings, paragraphs and list items
+	InputModeHTML = "html"
+
+	// CodeBlockSkip drops code blocks from markdown and html input
+	CodeBlockSkip = "skip"
+	// CodeBlockAnnounce replaces code blocks with a short spoken notice
+	CodeBlockAnnounce = "announce"
+)
+
+var codeBlockAnnouncements = map[string]string{
+	"zh": "此处有一段代码",
+	"en": "code block",
+}
+
+// toSpeakable converts markdown or html input into plain text with pause markers.
+func (c *Communicate) toSpeakable(text string) string {
+	announcement := ""
+	if c.CodeBlocks == CodeBlockAnnounce {
+		announcement = codeBlockAnnouncements[strings.SplitN(voiceLocale(c.VoiceLangRegion), "-", 2)[0]]
+		if announcement == "" {
+			announcement = codeBlockAnnouncements["en"]
+		}
+	}
+
+	switch c.InputMode {
+	case InputModeMarkdown:
+		return collapsePauses(markdownToSpeakable(text, announcement))
+	case InputModeHTML:
+		return collapsePauses(htmlToSpeakable(text, announcement))
+	default:
+		return text
+	}
+}
+
+// collapsePauses squeezes whitespace and keeps only the longest of adjacent pause markers.
+func collapsePauses(text string) string {
+	var b strings.Builder
+	var pending rune
+	space := false
+	for _, r := range text {
+		switch {
+		case isPause(r):
+			if r > pending {
+				pending = r
+			}
+			space = false
+		case r == ' ' || r == '\t' || r == '\n' || r == '\r':
+			space = pending == 0 && b.Len() > 0
+		default:
+			if pending != 0 {
+				if b.Len() > 0 {
+					b.WriteRune(pending)
+					b.WriteByte(' ')
+				}
+				pending = 0
+			} else if space {
+				b.WriteByte(' ')
+			}
+			space = false
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+var (
+	mdFencePattern     = regexp.MustCompile("^\\s*(```|~~~)")
+	mdHeadingPattern   = regexp.MustCompile(`^\s{0,3}#{1,6}\s+(.*?)\s*#*\s*$`)
+	mdListPattern      = regexp.MustCompile(`^\s*(?:[-*+]|\d+[.)])\s+(.*)$`)
+	mdRulePattern      = regexp.MustCompile(`^\s{0,3}(?:(?:-\s*){3,}|(?:\*\s*){3,}|(?:_\s*){3,}|(?:=\s*){3,})$`)
+	mdQuotePattern     = regexp.MustCompile(`^\s*(?:>\s?)+`)
+	mdTableSepPattern  = regexp.MustCompile(`^\s*\|?\s*:?-+:?\s*(?:\|\s*:?-+:?\s*)*\|?\s*$`)
+	mdImagePattern     = regexp.MustCompile(`!\[([^\]]*)\]\([^)]*\)`)
+	mdLinkPattern      = regexp.MustCompile(`\[([^\]]+)\](?:\([^)]*\)|\[[^\]]*\])`)
+	mdAutoLinkPattern  = regexp.MustCompile(`<(?:https?|mailto):[^>]+>`)
+	mdURLPattern       = regexp.MustCompile(`https?://[^\s)\]]+`)
+	mdCodePattern      = regexp.MustCompile("`+([^`]+)`+")
+	mdStrongPattern    = regexp.MustCompile(`\*\*([^*]+)\*\*|__([^_]+)__`)
+	mdEmphasisPattern  = regexp.MustCompile(`\*([^*\s][^*]*)\*|(^|[^\pL\pN_])_([^_]+)_($|[^\pL\pN_])`)
+	mdStrikePattern    = regexp.MustCompile(`~~([^~]+)~~`)
+	mdInlineTagPattern = regexp.MustCompile(`</?[a-zA-Z][^>]*>`)
+	mdEscapePattern    = regexp.MustCompile("\\\\([\\\\`*_{}\\[\\]()#+\\-.!|>~])")
+)
+
+func markdownToSpeakable(text, announcement string) string {
+	var b strings.Builder
+	inFence := false
+	for _, line := range strings.Split(text, "\n") {
+		if mdFencePattern.MatchString(line) {
+			if !inFence && announcement != "" {
+				b.WriteRune(pauseParagraph)
+				b.WriteString(announcement)
+			}
+			b.WriteRune(pauseParagraph)
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+
+		switch {
+		case strings.TrimSpace(line) == "", mdRulePattern.MatchString(line), mdTableSepPattern.MatchString(line) && strings.Contains(line, "-"):
+			b.WriteRune(pauseParagraph)
+		case mdHeadingPattern.MatchString(line):
+			b.WriteString(markdownInline(mdHeadingPattern.FindStringSubmatch(line)[1]))
+			b.WriteRune(pauseHeading)
+		case mdListPattern.MatchString(line):
+			b.WriteString(markdownInline(mdListPattern.FindStringSubmatch(line)[1]))
+			b.WriteRune(pauseListItem)
+		default:
+			line = mdQuotePattern.ReplaceAllString(line, "")
+			if strings.HasPrefix(strings.TrimSpace(line), "|") {
+				line = strings.Join(strings.FieldsFunc(line, func(r rune) bool { return r == '|' }), ", ")
+			}
+			b.WriteString(markdownInline(line))
+			b.WriteByte(' ')
+		}
+	}
+	return b.String()
+}
+
+func markdownInline(text string) string {
+	text = mdImagePattern.ReplaceAllString(text, "$1")
+	text = mdLinkPattern.ReplaceAllString(text, "$1")
+	text = mdAutoLinkPattern.ReplaceAllString(text, "")
+	text = mdURLPattern.ReplaceAllString(text, "")
+	text = mdCodePattern.ReplaceAllString(text, "$1")
+	text = mdStrongPattern.ReplaceAllString(text, "$1$2")
+	text = mdEmphasisPattern.ReplaceAllString(text, "$1$2$3$4")
+	text = mdStrikePattern.ReplaceAllString(text, "$1")
+	text = mdInlineTagPattern.ReplaceAllString(text, "")
+	text = mdEscapePattern.ReplaceAllString(text, "$1")
+	return text
+}
+
+var (
+	htmlDropPattern = regexp.MustCompile(`(?is)<!--.*?-->|<(script|style|head|template|noscript)\b[^>]*>.*?</(?:script|style|head|template|noscript)\s*>`)
+	htmlPrePattern  = regexp.MustCompile(`(?is)<pre\b[^>]*>.*?</pre\s*>`)
+	htmlImgPattern  = regexp.MustCompile(`(?is)<img\b[^>]*?\balt\s*=\s*(?:"([^"]*)"|'([^']*)')[^>]*>`)
+	htmlTagPattern  = regexp.MustCompile(`(?is)<(/?)([a-z][a-z0-9]*)\b[^>]*>`)
+)
+
+func htmlToSpeakable(text, announcement string) string {
+	text = htmlDropPattern.ReplaceAllString(text, "")
+	text = htmlPrePattern.ReplaceAllStringFunc(text, func(string) string {
+		if announcement == "" {
+			return string(pauseParagraph)
+		}
+		return string(pauseParagraph) + announcement + string(pauseParagraph)
+	})
+	text = htmlImgPattern.ReplaceAllString(text, " $1$2 ")
+	text = htmlTagPattern.ReplaceAllStringFunc(text, func(tag string) string {
+		m := htmlTagPattern.FindStringSubmatch(tag)
+		closing := m[1] == "/"
+		switch strings.ToLower(m[2]) {
+		case "h1", "h2", "h3", "h4", "h5", "h6":
+			return string(pauseHeading)
+		case "li", "dt", "dd":
+			if closing {
+				return string(pauseListItem)
+			}
+		case "p", "div", "section", "article", "blockquote", "ul", "ol", "dl", "table", "tr", "br", "hr", "header", "footer", "figure", "figcaption":
+			return string(pauseParagraph)
+		case "td", "th":
+			if closing {
+				return ", "
+			}
+		}
+		return ""
+	})
+	return html.UnescapeString(text)
+}
@@ -0,0 +1,175 @@
+package edge
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// Normalizer rewrites written forms such as numbers, dates and currency into the words they should be spoken as.
+// Normalizers run on the raw input text, before it is escaped and split.
+type Normalizer interface {
+	Normalize(text string) string
+}
+
+// NormalizerFunc adapts an ordinary function to a Normalizer.
+type NormalizerFunc func(text string) string
+
+func (f NormalizerFunc) Normalize(text string) string {
+	return f(text)
+}
+
+// NormalizerChain runs its normalizers in order, a nil chain leaves the text untouched.
+type NormalizerChain []Normalizer
+
+func (chain NormalizerChain) Normalize(text string) string {
+	for _, n := range chain {
+		text = n.Normalize(text)
+	}
+	return text
+}
+
+var (
+	localeNormalizersMu sync.RWMutex
+	localeNormalizers   = map[string]NormalizerChain{}
+)
+
+func init() {
+	RegisterNormalizers("en-US",
+		NormalizerFunc(enAbbreviations),
+		NormalizerFunc(enCurrency),
+		NormalizerFunc(enDates),
+		NormalizerFunc(enTimes),
+		NormalizerFunc(enUnits),
+		NormalizerFunc(enFractions),
+		NormalizerFunc(stripThousandsSeparators),
+	)
+	// the zh rules read the digits, so the separators go first
+	RegisterNormalizers("zh-CN",
+		NormalizerFunc(stripThousandsSeparators),
+		NormalizerFunc(zhAbbreviations),
+		NormalizerFunc(zhCurrency),
+		NormalizerFunc(zhDates),
+		NormalizerFunc(zhTimes),
+		NormalizerFunc(zhUnits),
+		NormalizerFunc(zhFractions),
+	)
+}
+
+// RegisterNormalizers appends normalizers to the chain used for voices of the locale, e.g. zh-CN.
+func RegisterNormalizers(locale string, normalizers ...Normalizer) {
+	localeNormalizersMu.Lock()
+	defer localeNormalizersMu.Unlock()
+	localeNormalizers[locale] = append(localeNormalizers[locale], normalizers...)
+}
+
+// NormalizersForLocale returns the chain registered for the locale,
+// falling back to another locale of the same language, e.g. en-GB uses en-US.
+// Among several locales of the language, the first in sorted order is used.
+func NormalizersForLocale(locale string) NormalizerChain {
+	localeNormalizersMu.RLock()
+	defer localeNormalizersMu.RUnlock()
+	if chain, ok := localeNormalizers[locale]; ok {
+		return append(NormalizerChain(nil), chain...)
+	}
+	lang := strings.SplitN(locale, "-", 2)[0]
+	locales := make([]string, 0, len(localeNormalizers))
+	for l := range localeNormalizers {
+		locales = append(locales, l)
+	}
+	sort.Strings(locales)
+	for _, l := range locales {
+		if strings.SplitN(l, "-", 2)[0] == lang {
+			return append(NormalizerChain(nil), localeNormalizers[l]...)
+		}
+	}
+	return nil
+}
+
+// thousandsPattern matches a number with separators, not a list of numbers such as 2019,2020.
+var thousandsPattern = regexp.MustCompile(`(^|[^\d,])(\d{1,3}(?:,\d{3})+)($|[^\d,])`)
+
+func stripThousandsSeparators(text string) string {
+	// a match takes the character after it, so numbers one character apart need another pass
+	for {
+		stripped := thousandsPattern.ReplaceAllStringFunc(text, func(s string) string {
+			return strings.ReplaceAll(s, ",", "")
+		})
+		if stripped == text {
+			return text
+		}
+		text = stripped
+	}
+}
+
+// wordReplacer replaces whole-word occurrences of the keys of a table, longest key first. The keys
+// are compiled once into an alternation that finds where a key may start.
+type wordReplacer struct {
+	table   map[string]string
+	keys    []string
+	pattern *regexp.Regexp
+}
+
+func newWordReplacer(table map[string]string) *wordReplacer {
+	keys := make([]string, 0, len(table))
+	for k := range table {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if len(keys[i]) != len(keys[j]) {
+			return len(keys[i]) > len(keys[j])
+		}
+		return keys[i] < keys[j]
+	})
+	quoted := make([]string, len(keys))
+	for i, k := range keys {
+		quoted[i] = regexp.QuoteMeta(k)
+	}
+	return &wordReplacer{table: table, keys: keys, pattern: regexp.MustCompile(strings.Join(quoted, "|"))}
+}
+
+func (r *wordReplacer) Replace(text string) string {
+	var b strings.Builder
+	last := 0
+	for start := 0; start < len(text); {
+		loc := r.pattern.FindStringIndex(text[start:])
+		if loc == nil {
+			break
+		}
+		i := start + loc[0]
+		key := r.wordAt(text, i)
+		if key == "" {
+			_, size := utf8.DecodeRuneInString(text[i:])
+			start = i + size
+			continue
+		}
+		b.WriteString(text[last:i])
+		b.WriteString(r.table[key])
+		last = i + len(key)
+		start = last
+	}
+	if last == 0 {
+		return text
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+// wordAt returns the longest key at i that is neither preceded nor followed by a letter or a number.
+func (r *wordReplacer) wordAt(text string, i int) string {
+	if before, _ := utf8.DecodeLastRuneInString(text[:i]); i > 0 && isWordRune(before) {
+		return ""
+	}
+	for _, k := range r.keys {
+		if !strings.HasPrefix(text[i:], k) {
+			continue
+		}
+		if after, _ := utf8.DecodeRuneInString(text[i+len(k):]); i+len(k) < len(text) && isWordRune(after) {
+			continue
+		}
+		return k
+	}
+	return ""
+}
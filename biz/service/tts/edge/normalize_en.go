@@ -0,0 +1,268 @@
+package edge
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var enAbbreviationTable = map[string]string{
+	"Mr.":     "Mister",
+	"Mrs.":    "Missus",
+	"Dr.":     "Doctor",
+	"Prof.":   "Professor",
+	"St.":     "Saint",
+	"Jr.":     "Junior",
+	"Sr.":     "Senior",
+	"vs.":     "versus",
+	"etc.":    "et cetera",
+	"e.g.":    "for example",
+	"i.e.":    "that is",
+	"approx.": "approximately",
+	"No.":     "number",
+}
+
+var enAbbreviationWords = newWordReplacer(enAbbreviationTable)
+
+func enAbbreviations(text string) string {
+	return enAbbreviationWords.Replace(text)
+}
+
+var (
+	enCurrencyPattern = regexp.MustCompile(`([$€£¥])(\d[\d,]*)(?:\.(\d{1,2}))?(?:\s?(thousand|million|billion|[kKmMbB]n?)\b)?`)
+	enCurrencyNames   = map[string][2]string{
+		"$": {"dollar", "cent"},
+		"€": {"euro", "cent"},
+		"£": {"pound", "penny"},
+		"¥": {"yuan", "fen"},
+	}
+	enScaleNames = map[string]string{
+		"k": "thousand", "K": "thousand",
+		"m": "million", "M": "million",
+		"b": "billion", "B": "billion", "bn": "billion", "Bn": "billion",
+	}
+)
+
+// enCurrency reads $1,234.50 as 1234 dollars and 50 cents, and $3.5M as 3.5 million dollars.
+func enCurrency(text string) string {
+	return enCurrencyPattern.ReplaceAllStringFunc(text, func(s string) string {
+		m := enCurrencyPattern.FindStringSubmatch(s)
+		names := enCurrencyNames[m[1]]
+		whole := strings.ReplaceAll(m[2], ",", "")
+		if m[4] != "" {
+			scale := m[4]
+			if name, ok := enScaleNames[scale]; ok {
+				scale = name
+			}
+			amount := whole
+			if m[3] != "" {
+				amount += "." + m[3]
+			}
+			return fmt.Sprintf("%s %s %ss", amount, scale, names[0])
+		}
+		spoken := whole + " " + enPlural(names[0], whole)
+		if cents := strings.TrimLeft(m[3], "0"); cents != "" {
+			if len(m[3]) == 1 {
+				cents += "0"
+			}
+			spoken += " and " + cents + " " + enPlural(names[1], cents)
+		}
+		return spoken
+	})
+}
+
+func enPlural(word, count string) string {
+	if count == "1" {
+		return word
+	}
+	if word == "penny" {
+		return "pence"
+	}
+	return word + "s"
+}
+
+var (
+	enISODatePattern = regexp.MustCompile(`\b(\d{4})-(\d{1,2})-(\d{1,2})\b`)
+	enUSDatePattern  = regexp.MustCompile(`\b(\d{1,2})/(\d{1,2})/(\d{4})\b`)
+	enMonths         = []string{"January", "February", "March", "April", "May", "June", "July", "August", "September", "October", "November", "December"}
+)
+
+// enDates reads 2023-07-01 and 7/1/2023 as July 1, 2023.
+func enDates(text string) string {
+	spoken := func(s, year, month, day string) string {
+		m, _ := strconv.Atoi(month)
+		d, _ := strconv.Atoi(day)
+		if m < 1 || m > 12 || d < 1 || d > 31 {
+			return s
+		}
+		return fmt.Sprintf("%s %d, %s", enMonths[m-1], d, year)
+	}
+	text = enISODatePattern.ReplaceAllStringFunc(text, func(s string) string {
+		m := enISODatePattern.FindStringSubmatch(s)
+		return spoken(s, m[1], m[2], m[3])
+	})
+	return enUSDatePattern.ReplaceAllStringFunc(text, func(s string) string {
+		m := enUSDatePattern.FindStringSubmatch(s)
+		return spoken(s, m[3], m[1], m[2])
+	})
+}
+
+// enTimePattern matches a time after a word such as "at" or "until" or before AM or PM, other N:NN such as
+// John 3:16 or a score of 2:1 are not times.
+var enTimePattern = regexp.MustCompile(`(\b(?i:at|by|from|to|until|till|before|after|around|about|since)\s+)?\b([01]?\d|2[0-3]):([0-5]\d)\b(\s?[aApP]\.?[mM]\.?)?`)
+
+// enTimes reads at 14:30 as at 2:30 PM and 09:05 am as 9 oh 5 AM.
+func enTimes(text string) string {
+	return enTimePattern.ReplaceAllStringFunc(text, func(s string) string {
+		m := enTimePattern.FindStringSubmatch(s)
+		if m[1] == "" && m[4] == "" {
+			return s
+		}
+		hour, _ := strconv.Atoi(m[2])
+		minute, _ := strconv.Atoi(m[3])
+		suffix := strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(m[4]), ".", ""))
+		if suffix == "" {
+			suffix = "AM"
+			if hour >= 12 {
+				suffix = "PM"
+			}
+			if hour > 12 {
+				hour -= 12
+			}
+			if hour == 0 {
+				hour = 12
+			}
+		}
+		switch {
+		case minute == 0:
+			return fmt.Sprintf("%s%d %s", m[1], hour, suffix)
+		case minute < 10:
+			return fmt.Sprintf("%s%d oh %d %s", m[1], hour, minute, suffix)
+		default:
+			return fmt.Sprintf("%s%d %d %s", m[1], hour, minute, suffix)
+		}
+	})
+}
+
+var (
+	enUnitPattern = regexp.MustCompile(`(\d+(?:\.\d+)?)\s?(km/h|mph|km|cm|mm|kg|mg|ml|°C|°F|m|g|l|L|%)($|[^\pL\pN])`)
+	enUnitNames   = map[string][2]string{
+		"km/h": {"kilometer per hour", "kilometers per hour"},
+		"mph":  {"mile per hour", "miles per hour"},
+		"km":   {"kilometer", "kilometers"},
+		"cm":   {"centimeter", "centimeters"},
+		"mm":   {"millimeter", "millimeters"},
+		"kg":   {"kilogram", "kilograms"},
+		"mg":   {"milligram", "milligrams"},
+		"ml":   {"milliliter", "milliliters"},
+		"°C":   {"degree Celsius", "degrees Celsius"},
+		"°F":   {"degree Fahrenheit", "degrees Fahrenheit"},
+		"m":    {"meter", "meters"},
+		"g":    {"gram", "grams"},
+		"l":    {"liter", "liters"},
+		"L":    {"liter", "liters"},
+		"%":    {"percent", "percent"},
+	}
+)
+
+// enUnits reads 5km as 5 kilometers and 12% as 12 percent.
+func enUnits(text string) string {
+	return enUnitPattern.ReplaceAllStringFunc(text, func(s string) string {
+		m := enUnitPattern.FindStringSubmatch(s)
+		names := enUnitNames[m[2]]
+		name := names[1]
+		if m[1] == "1" {
+			name = names[0]
+		}
+		return m[1] + " " + name + m[3]
+	})
+}
+
+var enFractionPattern = regexp.MustCompile(`(^|[^\d/])(\d{1,3})/(\d{1,3})($|[^\d/])`)
+
+// enFractions reads 3/4 as three quarters and 2/3 as two thirds, 24/7 is not a fraction.
+func enFractions(text string) string {
+	return enFractionPattern.ReplaceAllStringFunc(text, func(s string) string {
+		m := enFractionPattern.FindStringSubmatch(s)
+		num, _ := strconv.ParseInt(m[2], 10, 64)
+		den, _ := strconv.ParseInt(m[3], 10, 64)
+		if den < 2 || num >= den {
+			return s
+		}
+		var denominator string
+		switch den {
+		case 2:
+			denominator = "half"
+		case 4:
+			denominator = "quarter"
+		default:
+			denominator = enOrdinal(den)
+		}
+		if num != 1 {
+			if denominator == "half" {
+				denominator = "halves"
+			} else {
+				denominator += "s"
+			}
+		}
+		return m[1] + enInteger(num) + " " + denominator + m[4]
+	})
+}
+
+var (
+	enOnes = []string{"zero", "one", "two", "three", "four", "five", "six", "seven", "eight", "nine", "ten",
+		"eleven", "twelve", "thirteen", "fourteen", "fifteen", "sixteen", "seventeen", "eighteen", "nineteen"}
+	enTens   = []string{"", "", "twenty", "thirty", "forty", "fifty", "sixty", "seventy", "eighty", "ninety"}
+	enScales = []struct {
+		value int64
+		name  string
+	}{{1_000_000_000_000, "trillion"}, {1_000_000_000, "billion"}, {1_000_000, "million"}, {1_000, "thousand"}}
+)
+
+// enInteger spells a non-negative integer in English words.
+func enInteger(n int64) string {
+	if n < 20 {
+		return enOnes[n]
+	}
+	if n < 100 {
+		if n%10 == 0 {
+			return enTens[n/10]
+		}
+		return enTens[n/10] + "-" + enOnes[n%10]
+	}
+	if n < 1000 {
+		if n%100 == 0 {
+			return enOnes[n/100] + " hundred"
+		}
+		return enOnes[n/100] + " hundred " + enInteger(n%100)
+	}
+	for _, scale := range enScales {
+		if n >= scale.value {
+			if n%scale.value == 0 {
+				return enInteger(n/scale.value) + " " + scale.name
+			}
+			return enInteger(n/scale.value) + " " + scale.name + " " + enInteger(n%scale.value)
+		}
+	}
+	return strconv.FormatInt(n, 10)
+}
+
+var enOrdinalIrregular = map[string]string{
+	"one": "first", "two": "second", "three": "third", "five": "fifth",
+	"eight": "eighth", "nine": "ninth", "twelve": "twelfth",
+}
+
+// enOrdinal spells the ordinal of n, 3 -> third, 21 -> twenty-first.
+func enOrdinal(n int64) string {
+	words := enInteger(n)
+	idx := strings.LastIndexAny(words, " -") + 1
+	last := words[idx:]
+	if o, ok := enOrdinalIrregular[last]; ok {
+		return words[:idx] + o
+	}
+	if strings.HasSuffix(last, "y") {
+		return words[:idx] + strings.TrimSuffix(last, "y") + "ieth"
+	}
+	return words + "th"
+}
@@ -0,0 +1,54 @@
+package edge
+
+import "testing"
+
+func TestNormalizersForLocale(t *testing.T) {
+	cases := []struct {
+		locale string
+		text   string
+		want   string
+	}{
+		{"zh-CN", "¥1,234.5万", "一千二百三十四点五万元"},
+		{"zh-CN", "完成了3/4", "完成了四分之三"},
+		{"zh-CN", "2023-07-01 14:30", "二零二三年七月一日 十四点三十分"},
+		{"zh-CN", "增长12.5%，跑了10086km", "增长百分之十二点五，跑了一万零八十六公里"},
+		{"zh-CN", "跑了10,086km", "跑了一万零八十六公里"},
+		{"zh-CN", "比分3:16，下午3:05出发，9:05:30集合", "比分3:16，下午三点零五分出发，九点零五分三十秒集合"},
+		{"zh-CN", "版本2023.10.15发布于2023.10.16", "版本2023.10.15发布于二零二三年十月十六日"},
+		{"zh-CN", "增长1,234.5%", "增长百分之一千二百三十四点五"},
+		{"en-US", "Dr. Smith paid $1,234.50", "Doctor Smith paid 1234 dollars and 50 cents"},
+		{"en-US", "3/4 of 5km on 2023-07-01 at 14:05", "three quarters of 5 kilometers on July 1, 2023 at 2 oh 5 PM"},
+		{"en-US", "John 3:16 won 2:1, open from 9:00 to 17:30 or 10:15 p.m.", "John 3:16 won 2:1, open from 9 AM to 5 30 PM or 10 15 PM"},
+		{"en-US", "Mr. Dr. Smith, e.g. Mrs.X or ANo. 5", "Mister Doctor Smith, for example Mrs.X or ANo. 5"},
+		{"en-US", "open 24/7, 1/2 off", "open 24/7, one half off"},
+		{"zh-CN", "全天24/7营业", "全天24/7营业"},
+		{"en-GB", "1,000,000 people", "1000000 people"},
+		{"en-GB", "years 2019,2020,2021 and 1,000 2,000", "years 2019,2020,2021 and 1000 2000"},
+	}
+	for _, c := range cases {
+		got := NormalizersForLocale(c.locale).Normalize(c.text)
+		if got != c.want {
+			t.Errorf("Normalize(%s, %q) = %q, want %q", c.locale, c.text, got, c.want)
+		}
+	}
+}
+
+func TestWithNormalizers(t *testing.T) {
+	c, err := NewCommunicate("3/4", WithNormalizers())
+	if err != nil {
+		t.Fatalf("NewCommunicate fail, err: %v", err)
+	}
+	if got := c.prepareText(); got != "3/4" {
+		t.Errorf("normalization should be disabled, got %q", got)
+	}
+}
+
+func TestNormalizersFallback(t *testing.T) {
+	RegisterNormalizers("xx-BB", NormalizerFunc(func(string) string { return "BB" }))
+	RegisterNormalizers("xx-AA", NormalizerFunc(func(string) string { return "AA" }))
+	for i := 0; i < 10; i++ {
+		if got := NormalizersForLocale("xx-CC").Normalize(""); got != "AA" {
+			t.Fatalf("fallback of xx-CC = %q, want the first locale in order", got)
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package edge
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var zhAbbreviationTable = map[string]string{
+	"etc.": "等等",
+	"vs.":  "对",
+	"vs":   "对",
+	"No.":  "第",
+	"e.g.": "例如",
+}
+
+var zhAbbreviationWords = newWordReplacer(zhAbbreviationTable)
+
+func zhAbbreviations(text string) string {
+	return zhAbbreviationWords.Replace(text)
+}
+
+var (
+	zhCurrencyPattern = regexp.MustCompile(`(¥|￥|RMB|CNY|\$|US\$|€|£|HK\$)\s?(\d[\d,]*(?:\.\d+)?)\s?(万亿|亿|万|千)?(元|块)?`)
+	zhCurrencyNames   = map[string]string{
+		"¥": "元", "￥": "元", "RMB": "元", "CNY": "元",
+		"$": "美元", "US$": "美元", "HK$": "港元",
+		"€": "欧元", "£": "英镑",
+	}
+)
+
+// zhCurrency reads ¥1,234.5万 as 一千二百三十四点五万元.
+func zhCurrency(text string) string {
+	return zhCurrencyPattern.ReplaceAllStringFunc(text, func(s string) string {
+		m := zhCurrencyPattern.FindStringSubmatch(s)
+		return zhDecimal(strings.ReplaceAll(m[2], ",", "")) + m[3] + zhCurrencyNames[m[1]]
+	})
+}
+
+var (
+	zhISODatePattern = regexp.MustCompile(`(^|[^\d./-])(\d{4})[-/](\d{1,2})[-/](\d{1,2})($|[^\d./-])`)
+	// zhDottedDatePattern only reads 2023.10.15 as a date after a word such as 于, a version is written the same
+	zhDottedDatePattern = regexp.MustCompile(`((?:日期|于|在|截至|截止|从|到|至)[:：]?\s*)(\d{4})\.(\d{1,2})\.(\d{1,2})($|[^\d.])`)
+	zhDatePattern       = regexp.MustCompile(`(\d{4})年(\d{1,2})月(\d{1,2})[日号]`)
+)
+
+// zhDates reads 2023-07-01, 于2023.07.01 and 2023年7月1日 as 二零二三年七月一日.
+func zhDates(text string) string {
+	spoken := func(s, year, month, day string) string {
+		m, _ := strconv.Atoi(month)
+		d, _ := strconv.Atoi(day)
+		if m < 1 || m > 12 || d < 1 || d > 31 {
+			return s
+		}
+		return zhDigits(year) + "年" + zhInteger(int64(m)) + "月" + zhInteger(int64(d)) + "日"
+	}
+	for _, pattern := range []*regexp.Regexp{zhISODatePattern, zhDottedDatePattern} {
+		pattern := pattern
+		text = pattern.ReplaceAllStringFunc(text, func(s string) string {
+			m := pattern.FindStringSubmatch(s)
+			date := m[0][len(m[1]) : len(m[0])-len(m[5])]
+			return m[1] + spoken(date, m[2], m[3], m[4]) + m[5]
+		})
+	}
+	return zhDatePattern.ReplaceAllStringFunc(text, func(s string) string {
+		m := zhDatePattern.FindStringSubmatch(s)
+		return spoken(s, m[1], m[2], m[3])
+	})
+}
+
+// zhTimePattern matches a time after a word such as 下午 or a date, or with seconds. Other N:NN such as
+// a score of 3:16 are not times.
+var zhTimePattern = regexp.MustCompile(`((?:在|于|从|到|至|早上|上午|中午|下午|晚上|傍晚|凌晨|今天|明天|昨天|每天|日|号|时间[:：]?)\s*)?\b([01]?\d|2[0-3]):([0-5]\d)(?::([0-5]\d))?\b`)
+
+// zhTimes reads 下午14:30 as 下午十四点三十分 and 9:05:30 as 九点零五分三十秒.
+func zhTimes(text string) string {
+	return zhTimePattern.ReplaceAllStringFunc(text, func(s string) string {
+		m := zhTimePattern.FindStringSubmatch(s)
+		if m[1] == "" && m[4] == "" {
+			return s
+		}
+		hour, _ := strconv.Atoi(m[2])
+		minute, _ := strconv.Atoi(m[3])
+		spoken := m[1] + zhInteger(int64(hour)) + "点"
+		if minute == 0 && m[4] == "" {
+			return spoken + "整"
+		}
+		if minute < 10 {
+			spoken += "零"
+		}
+		spoken += zhInteger(int64(minute)) + "分"
+		if m[4] != "" {
+			second, _ := strconv.Atoi(m[4])
+			spoken += zhInteger(int64(second)) + "秒"
+		}
+		return spoken
+	})
+}
+
+var (
+	zhPercentPattern = regexp.MustCompile(`(\d+(?:\.\d+)?)\s?%`)
+	zhUnitPattern    = regexp.MustCompile(`(\d+(?:\.\d+)?)\s?(km/h|km|cm|mm|kg|mg|ml|℃|°C|m|g|L|l)($|[^\pL\pN])`)
+	zhUnitNames      = map[string]string{
+		"km/h": "公里每小时",
+		"km":   "公里",
+		"cm":   "厘米",
+		"mm":   "毫米",
+		"kg":   "公斤",
+		"mg":   "毫克",
+		"ml":   "毫升",
+		"℃":    "摄氏度",
+		"°C":   "摄氏度",
+		"m":    "米",
+		"g":    "克",
+		"L":    "升",
+		"l":    "升",
+	}
+)
+
+// zhUnits reads 12.5% as 百分之十二点五 and 5km as 五公里.
+func zhUnits(text string) string {
+	text = zhPercentPattern.ReplaceAllStringFunc(text, func(s string) string {
+		m := zhPercentPattern.FindStringSubmatch(s)
+		return "百分之" + zhDecimal(m[1])
+	})
+	return zhUnitPattern.ReplaceAllStringFunc(text, func(s string) string {
+		m := zhUnitPattern.FindStringSubmatch(s)
+		return zhDecimal(m[1]) + zhUnitNames[m[2]] + m[3]
+	})
+}
+
+var zhFractionPattern = regexp.MustCompile(`(^|[^\d/])(\d{1,4})/(\d{1,4})($|[^\d/])`)
+
+// zhFractions reads 3/4 as 四分之三, 24/7 is not a fraction.
+func zhFractions(text string) string {
+	return zhFractionPattern.ReplaceAllStringFunc(text, func(s string) string {
+		m := zhFractionPattern.FindStringSubmatch(s)
+		num, _ := strconv.ParseInt(m[2], 10, 64)
+		den, _ := strconv.ParseInt(m[3], 10, 64)
+		if den == 0 || num >= den {
+			return s
+		}
+		return m[1] + zhInteger(den) + "分之" + zhInteger(num) + m[4]
+	})
+}
+
+var (
+	zhDigitNames    = []string{"零", "一", "二", "三", "四", "五", "六", "七", "八", "九"}
+	zhSmallUnits    = []string{"", "十", "百", "千"}
+	zhSectionUnits  = []string{"", "万", "亿", "万亿"}
+	zhSectionDivide = int64(10000)
+)
+
+// zhDigits reads every digit on its own, 2023 -> 二零二三.
+func zhDigits(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			b.WriteString(zhDigitNames[r-'0'])
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// zhDecimal reads a decimal number, 1234.5 -> 一千二百三十四点五.
+func zhDecimal(s string) string {
+	whole, frac, hasFrac := strings.Cut(s, ".")
+	n, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil {
+		return s
+	}
+	spoken := zhInteger(n)
+	if hasFrac && frac != "" {
+		spoken += "点" + zhDigits(frac)
+	}
+	return spoken
+}
+
+// zhInteger reads a non-negative integer, 10 -> 十, 10086 -> 一万零八十六.
+func zhInteger(n int64) string {
+	if n == 0 {
+		return zhDigitNames[0]
+	}
+
+	var sections []int64
+	for n > 0 && len(sections) < len(zhSectionUnits) {
+		sections = append(sections, n%zhSectionDivide)
+		n /= zhSectionDivide
+	}
+
+	var b strings.Builder
+	needZero := false
+	for i := len(sections) - 1; i >= 0; i-- {
+		section := sections[i]
+		if section == 0 {
+			needZero = b.Len() > 0
+			continue
+		}
+		if needZero || (b.Len() > 0 && section < 1000) {
+			b.WriteString(zhDigitNames[0])
+		}
+		b.WriteString(zhSection(section))
+		b.WriteString(zhSectionUnits[i])
+		needZero = false
+	}
+
+	spoken := b.String()
+	// 一十二 is read as 十二
+	if strings.HasPrefix(spoken, "一十") {
+		spoken = strings.TrimPrefix(spoken, "一")
+	}
+	return spoken
+}
+
+// zhSection reads a number below 10000.
+func zhSection(n int64) string {
+	var b strings.Builder
+	zero := false
+	for i := 3; i >= 0; i-- {
+		divisor := int64(1)
+		for j := 0; j < i; j++ {
+			divisor *= 10
+		}
+		digit := (n / divisor) % 10
+		if digit == 0 {
+			zero = b.Len() > 0
+			continue
+		}
+		if zero {
+			b.WriteString(zhDigitNames[0])
+			zero = false
+		}
+		b.WriteString(zhDigitNames[digit])
+		b.WriteString(zhSmallUnits[i])
+	}
+	return b.String()
+}
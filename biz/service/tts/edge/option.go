@@ -3,6 +3,8 @@ package edge
 type Option struct {
 	OptID optionID
 	Param string
+	// Value carries options that can not be expressed as a string
+	Value interface{}
 }
 
 type optionID int
@@ -13,6 +15,22 @@ const (
 	optionIDVolume
 	optionIDProxy
 	optionIDPitch
+	optionIDStyle
+	optionIDStyleDegree
+	optionIDRole
+	optionIDNormalizers
+	optionIDInputMode
+	optionIDCodeBlocks
+	optionIDLexicon
+	optionIDSecondaryVoice
+	optionIDMultilingual
+	optionIDSentencePause
+	optionIDParagraphPause
+	optionIDChunkPause
+	optionIDPauseMarker
+	optionIDSanitizePolicy
+	optionIDProgress
+	optionIDOutputFormat
 )
 
 // WithVoice get voice config here: https://learn.microsoft.com/en-us/azure/cognitive-services/speech-service/language-support?tabs=tts
@@ -95,3 +113,294 @@ func GetPitchByOption(opts []Option) string {
 	}
 	return ""
 }
+
+// WithStyle sets the speaking style, e.g. cheerful, sad or whispering. The style must be listed for the voice in the voice catalog.
+func WithStyle(style string) Option {
+	return Option{
+		OptID: optionIDStyle,
+		Param: style,
+	}
+}
+
+func GetStyleByOption(opts []Option) string {
+	for _, opt := range opts {
+		if opt.OptID == optionIDStyle {
+			return opt.Param
+		}
+	}
+	return ""
+}
+
+// WithStyleDegree sets the intensity of the speaking style, from 0.01 to 2.
+func WithStyleDegree(degree string) Option {
+	return Option{
+		OptID: optionIDStyleDegree,
+		Param: degree,
+	}
+}
+
+func GetStyleDegreeByOption(opts []Option) string {
+	for _, opt := range opts {
+		if opt.OptID == optionIDStyleDegree {
+			return opt.Param
+		}
+	}
+	return ""
+}
+
+// WithRole sets the role-play of the voice, e.g. Girl or OlderAdultMale. The role must be listed for the voice in the voice catalog.
+func WithRole(role string) Option {
+	return Option{
+		OptID: optionIDRole,
+		Param: role,
+	}
+}
+
+func GetRoleByOption(opts []Option) string {
+	for _, opt := range opts {
+		if opt.OptID == optionIDRole {
+			return opt.Param
+		}
+	}
+	return ""
+}
+
+// WithNormalizers replaces the normalizers picked by the voice locale, pass none to disable normalization.
+func WithNormalizers(normalizers ...Normalizer) Option {
+	return Option{
+		OptID: optionIDNormalizers,
+		Value: NormalizerChain(normalizers),
+	}
+}
+
+func GetNormalizersByOption(opts []Option) (NormalizerChain, bool) {
+	for _, opt := range opts {
+		if opt.OptID == optionIDNormalizers {
+			return opt.Value.(NormalizerChain), true
+		}
+	}
+	return nil, false
+}
+
+// WithInputMode sets how the text is read, one of InputModeText, InputModeMarkdown and InputModeHTML.
+func WithInputMode(mode string) Option {
+	return Option{
+		OptID: optionIDInputMode,
+		Param: mode,
+	}
+}
+
+func GetInputModeByOption(opts []Option) string {
+	for _, opt := range opts {
+		if opt.OptID == optionIDInputMode {
+			return opt.Param
+		}
+	}
+	return ""
+}
+
+// WithCodeBlocks sets how code blocks in markdown and html input are read, CodeBlockSkip or CodeBlockAnnounce.
+func WithCodeBlocks(mode string) Option {
+	return Option{
+		OptID: optionIDCodeBlocks,
+		Param: mode,
+	}
+}
+
+func GetCodeBlocksByOption(opts []Option) string {
+	for _, opt := range opts {
+		if opt.OptID == optionIDCodeBlocks {
+			return opt.Param
+		}
+	}
+	return ""
+}
+
+// WithLexicon sets custom pronunciations, they take precedence over the global lexicon.
+func WithLexicon(lexicon *Lexicon) Option {
+	return Option{
+		OptID: optionIDLexicon,
+		Value: lexicon,
+	}
+}
+
+func GetLexiconByOption(opts []Option) *Lexicon {
+	for _, opt := range opts {
+		if opt.OptID == optionIDLexicon {
+			return opt.Value.(*Lexicon)
+		}
+	}
+	return nil
+}
+
+// WithSecondaryVoice sets the voice that reads runs of text in its language, e.g. en-US-GuyNeural for english words
+// in chinese text, can be given once per language.
+func WithSecondaryVoice(voice string) Option {
+	return Option{
+		OptID: optionIDSecondaryVoice,
+		Param: voice,
+	}
+}
+
+func GetSecondaryVoicesByOption(opts []Option) []string {
+	var voices []string
+	for _, opt := range opts {
+		if opt.OptID == optionIDSecondaryVoice {
+			voices = append(voices, opt.Param)
+		}
+	}
+	return voices
+}
+
+// WithMultilingual marks the voice as multilingual when the voice catalog does not know it.
+func WithMultilingual() Option {
+	return Option{
+		OptID: optionIDMultilingual,
+	}
+}
+
+func GetMultilingualByOption(opts []Option) bool {
+	for _, opt := range opts {
+		if opt.OptID == optionIDMultilingual {
+			return true
+		}
+	}
+	return false
+}
+
+// WithSentencePause sets the silence after every sentence, e.g. 300ms
+func WithSentencePause(duration string) Option {
+	return Option{
+		OptID: optionIDSentencePause,
+		Param: duration,
+	}
+}
+
+func GetSentencePauseByOption(opts []Option) string {
+	for _, opt := range opts {
+		if opt.OptID == optionIDSentencePause {
+			return opt.Param
+		}
+	}
+	return ""
+}
+
+// WithParagraphPause sets the silence after every paragraph, in plain text paragraphs are separated by blank lines.
+func WithParagraphPause(duration string) Option {
+	return Option{
+		OptID: optionIDParagraphPause,
+		Param: duration,
+	}
+}
+
+func GetParagraphPauseByOption(opts []Option) string {
+	for _, opt := range opts {
+		if opt.OptID == optionIDParagraphPause {
+			return opt.Param
+		}
+	}
+	return ""
+}
+
+// WithChunkPause sets the silence between the chunks a long text is split into, it defaults to the sentence pause.
+func WithChunkPause(duration string) Option {
+	return Option{
+		OptID: optionIDChunkPause,
+		Param: duration,
+	}
+}
+
+func GetChunkPauseByOption(opts []Option) string {
+	for _, opt := range opts {
+		if opt.OptID == optionIDChunkPause {
+			return opt.Param
+		}
+	}
+	return ""
+}
+
+// WithPauseMarker reads every occurrence of marker in the text as a silence of duration, can be given many times.
+func WithPauseMarker(marker, duration string) Option {
+	return Option{
+		OptID: optionIDPauseMarker,
+		Param: marker,
+		Value: duration,
+	}
+}
+
+func GetPauseMarkersByOption(opts []Option) []PauseMarker {
+	var markers []PauseMarker
+	for _, opt := range opts {
+		if opt.OptID == optionIDPauseMarker {
+			markers = append(markers, PauseMarker{Marker: opt.Param, Duration: opt.Value.(string)})
+		}
+	}
+	return markers
+}
+
+// WithSanitizePolicy sets what happens to control characters, emoji, zero-width characters and symbols,
+// see DefaultSanitizePolicy for the defaults of empty fields.
+func WithSanitizePolicy(policy SanitizePolicy) Option {
+	return Option{
+		OptID: optionIDSanitizePolicy,
+		Value: policy,
+	}
+}
+
+func GetSanitizePolicyByOption(opts []Option) SanitizePolicy {
+	policy := DefaultSanitizePolicy()
+	for _, opt := range opts {
+		if opt.OptID == optionIDSanitizePolicy {
+			p := opt.Value.(SanitizePolicy)
+			if p.Control != "" {
+				policy.Control = p.Control
+			}
+			if p.Emoji != "" {
+				policy.Emoji = p.Emoji
+			}
+			if p.ZeroWidth != "" {
+				policy.ZeroWidth = p.ZeroWidth
+			}
+			if p.Symbols != "" {
+				policy.Symbols = p.Symbols
+			}
+			policy.Names = p.Names
+			return policy
+		}
+	}
+	return policy
+}
+
+// WithProgress reports the progress of Stream, see ProgressChannel to receive it on a channel.
+func WithProgress(f ProgressFunc) Option {
+	return Option{
+		OptID: optionIDProgress,
+		Value: f,
+	}
+}
+
+func GetProgressByOption(opts []Option) ProgressFunc {
+	for _, opt := range opts {
+		if opt.OptID == optionIDProgress {
+			return opt.Value.(ProgressFunc)
+		}
+	}
+	return nil
+}
+
+// WithOutputFormat sets the audio format asked from the service, see DefaultOutputFormat.
+func WithOutputFormat(format string) Option {
+	return Option{
+		OptID: optionIDOutputFormat,
+		Param: format,
+	}
+}
+
+func GetOutputFormatByOption(opts []Option) string {
+	for _, opt := range opts {
+		if opt.OptID == optionIDOutputFormat {
+			return opt.Param
+		}
+	}
+	return ""
+}
@@ -0,0 +1,168 @@
+package edge
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Pause markers are private use characters that survive normalization and escaping,
+// they are expanded into <break> elements right before the text is split.
+// Markers of markup structure are ordered by strength, see collapsePauses.
+const (
+	pauseSentence  = '\uE000'
+	pauseListItem  = '\uE001'
+	pauseParagraph = '\uE002'
+	pauseHeading   = '\uE003'
+	// pauseMarker is the marker of the first WithPauseMarker, the following ones count up from here
+	pauseMarker = '\uE010'
+	pauseLast   = '\uE0FF'
+)
+
+var defaultPauses = map[rune]string{
+	pauseSentence:  "200ms",
+	pauseListItem:  "300ms",
+	pauseParagraph: "500ms",
+	pauseHeading:   "700ms",
+}
+
+// PauseMarker is a literal text that is read as a silence, e.g. [pause]
+type PauseMarker struct {
+	Marker   string
+	Duration string
+}
+
+var pauseDurationPattern = regexp.MustCompile(`^(\d+)(ms|s)$`)
+
+func validatePause(duration string) error {
+	if duration != "" && !pauseDurationPattern.MatchString(duration) {
+		return errors.New("invalid pause " + duration + ", must be like 500ms or 2s")
+	}
+	return nil
+}
+
+// pauseMillis converts a validated duration to milliseconds.
+func pauseMillis(duration string) int {
+	m := pauseDurationPattern.FindStringSubmatch(duration)
+	if m == nil {
+		return 0
+	}
+	n, _ := strconv.Atoi(m[1])
+	if m[2] == "s" {
+		n *= 1000
+	}
+	return n
+}
+
+func isPause(r rune) bool {
+	return r >= pauseSentence && r <= pauseLast
+}
+
+// pauseDuration returns the configured duration of a pause marker.
+func (c *Communicate) pauseDuration(r rune) string {
+	switch {
+	case r >= pauseMarker:
+		if idx := int(r - pauseMarker); idx < len(c.PauseMarkers) {
+			return c.PauseMarkers[idx].Duration
+		}
+		return ""
+	case r == pauseSentence && c.SentencePause != "":
+		return c.SentencePause
+	case r == pauseParagraph && c.ParagraphPause != "":
+		return c.ParagraphPause
+	default:
+		return defaultPauses[r]
+	}
+}
+
+// markPauses replaces explicit pause markers, and marks blank lines in plain text as paragraph pauses
+// when a paragraph pause is configured.
+func (c *Communicate) markPauses(text string) string {
+	for i, m := range c.PauseMarkers {
+		text = strings.ReplaceAll(text, m.Marker, string(pauseMarker+rune(i)))
+	}
+	if c.InputMode == InputModeText && c.ParagraphPause != "" {
+		text = paragraphPattern.ReplaceAllString(text, string(pauseParagraph)+" ")
+	}
+	return text
+}
+
+var paragraphPattern = regexp.MustCompile(`[ \t]*\r?\n[ \t]*(?:\r?\n[ \t]*)+`)
+
+// markSentences marks the end of every sentence when a sentence pause is configured, it runs after
+// normalization so that abbreviations like Dr. are no longer taken as the end of a sentence.
+func (c *Communicate) markSentences(text string) string {
+	if c.SentencePause == "" {
+		return text
+	}
+	runes := []rune(text)
+	var b strings.Builder
+	for i, r := range runes {
+		b.WriteRune(r)
+		switch r {
+		case '。', '！', '？', '；':
+			if i+1 < len(runes) && !isPause(runes[i+1]) && !isClosingPunct(runes[i+1]) {
+				b.WriteRune(pauseSentence)
+			}
+		case '.', '!', '?', ';':
+			if i+1 < len(runes) && unicode.IsSpace(runes[i+1]) {
+				b.WriteRune(pauseSentence)
+			}
+		}
+	}
+	return b.String()
+}
+
+func isClosingPunct(r rune) bool {
+	return strings.ContainsRune(`"'”’」』）)】`, r)
+}
+
+// expandPauses replaces pause markers in escaped text with <break> elements, markers that are only
+// separated by spaces become one break of the longest duration.
+func (c *Communicate) expandPauses(text string) string {
+	if strings.IndexFunc(text, isPause) == -1 {
+		return text
+	}
+	runes := []rune(text)
+	var b strings.Builder
+	for i := 0; i < len(runes); i++ {
+		if !isPause(runes[i]) {
+			b.WriteRune(runes[i])
+			continue
+		}
+		longest := ""
+		space := false
+		for ; i < len(runes) && (isPause(runes[i]) || unicode.IsSpace(runes[i])); i++ {
+			if unicode.IsSpace(runes[i]) {
+				space = true
+			} else if d := c.pauseDuration(runes[i]); pauseMillis(d) > pauseMillis(longest) {
+				longest = d
+			}
+		}
+		i--
+		if longest != "" {
+			fmt.Fprintf(&b, "<break time='%s'/>", longest)
+		}
+		if space {
+			b.WriteByte(' ')
+		}
+	}
+	return b.String()
+}
+
+// chunkText returns the ssml text of a chunk, every chunk but the last ends with the chunk pause
+// so that splitting a long text does not run the chunks together.
+func (c *Communicate) chunkText(texts [][]byte, idx int) string {
+	text := string(texts[idx])
+	pause := c.ChunkPause
+	if pause == "" {
+		pause = c.SentencePause
+	}
+	if pause == "" || idx == len(texts)-1 || strings.HasSuffix(text, "/>") {
+		return text
+	}
+	return text + fmt.Sprintf("<break time='%s'/>", pause)
+}
@@ -0,0 +1,77 @@
+package edge
+
+import (
+	"sync"
+	"time"
+)
+
+// Progress is a snapshot of a synthesis, reported every time a chunk starts or finishes and every time audio arrives.
+type Progress struct {
+	ChunksTotal    int
+	ChunksStarted  int
+	ChunksFinished int
+	BytesReceived  int64
+	// AudioSeconds is estimated from the bytes received
+	AudioSeconds float64
+	// UpdatedAt is when the last event happened, a job that does not move is stuck
+	UpdatedAt time.Time
+}
+
+// ProgressFunc receives progress reports, it is called from the goroutines reading the chunks
+// one report at a time, and should return quickly.
+type ProgressFunc func(Progress)
+
+// ProgressChannel adapts a channel to ProgressFunc, reports are dropped while the channel is full.
+func ProgressChannel(ch chan<- Progress) ProgressFunc {
+	return func(p Progress) {
+		select {
+		case ch <- p:
+		default:
+		}
+	}
+}
+
+type progressTracker struct {
+	mu       sync.Mutex
+	progress Progress
+	report   ProgressFunc
+	format   string
+}
+
+func newProgressTracker(report ProgressFunc, format string, chunks int) *progressTracker {
+	t := &progressTracker{report: report, format: format}
+	t.update(func(p *Progress) {
+		p.ChunksTotal = chunks
+	})
+	return t
+}
+
+func (t *progressTracker) update(f func(p *Progress)) {
+	if t.report == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	f(&t.progress)
+	t.progress.UpdatedAt = time.Now()
+	t.report(t.progress)
+}
+
+func (t *progressTracker) started() {
+	t.update(func(p *Progress) {
+		p.ChunksStarted++
+	})
+}
+
+func (t *progressTracker) finished() {
+	t.update(func(p *Progress) {
+		p.ChunksFinished++
+	})
+}
+
+func (t *progressTracker) received(n int) {
+	t.update(func(p *Progress) {
+		p.BytesReceived += int64(n)
+		p.AudioSeconds = AudioSeconds(t.format, p.BytesReceived)
+	})
+}
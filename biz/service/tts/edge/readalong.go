@@ -0,0 +1,131 @@
+package edge
+
+import (
+	"encoding/json"
+	"html"
+	"strings"
+	"unicode"
+)
+
+// readAlongWindow is how many characters past the last matched word a word is looked for,
+// so a word that was rewritten by a normalizer does not match a far later occurrence.
+const readAlongWindow = 64
+
+// ReadAlongWord is a spoken word with its time in milliseconds and its range in the original text.
+// CharStart and CharEnd count unicode code points, End is exclusive.
+type ReadAlongWord struct {
+	Text      string `json:"text"`
+	Start     int64  `json:"start"`
+	End       int64  `json:"end"`
+	CharStart int    `json:"char_start"`
+	CharEnd   int    `json:"char_end"`
+}
+
+// ReadAlong maps the words of the audio to the text they were read from.
+type ReadAlong struct {
+	Text  string          `json:"text"`
+	Words []ReadAlongWord `json:"words"`
+}
+
+func (r ReadAlong) JSON() ([]byte, error) {
+	return json.MarshalIndent(r, "", "  ")
+}
+
+// ReadAlong aligns the word boundaries of a Stream to Text, the original input before it was sanitized,
+// normalized and escaped. The service reports the words as they were read, so each one is looked for
+// after the previous one. Words a normalizer wrote, such as "twenty three" for 23, are not found and
+// share the range between the words around them.
+func (c *Communicate) ReadAlong(boundaries []Boundary) ReadAlong {
+	text := []rune(c.Text)
+	lower := make([]rune, len(text))
+	for i, r := range text {
+		lower[i] = unicode.ToLower(r)
+	}
+
+	r := ReadAlong{Text: c.Text, Words: []ReadAlongWord{}}
+	matched := []bool{}
+	cursor := 0
+	for _, b := range boundaries {
+		if b.Type == BoundarySentence {
+			continue
+		}
+		word := strings.TrimSpace(html.UnescapeString(b.Text))
+		if word == "" {
+			continue
+		}
+		w := ReadAlongWord{
+			Text:  word,
+			Start: b.Offset.Milliseconds(),
+			End:   (b.Offset + b.Duration).Milliseconds(),
+		}
+		start := indexRunes(lower, []rune(strings.ToLower(word)), cursor, cursor+readAlongWindow)
+		if start >= 0 {
+			w.CharStart, w.CharEnd = start, start+len([]rune(word))
+			cursor = w.CharEnd
+		}
+		r.Words = append(r.Words, w)
+		matched = append(matched, start >= 0)
+	}
+
+	// unmatched words share the text between the matched words around them
+	for i := 0; i < len(r.Words); {
+		if matched[i] {
+			i++
+			continue
+		}
+		j := i
+		for j < len(r.Words) && !matched[j] {
+			j++
+		}
+		from, to := 0, len(text)
+		if i > 0 {
+			from = r.Words[i-1].CharEnd
+		}
+		if j < len(r.Words) {
+			to = r.Words[j].CharStart
+		}
+		for from < to && !isWordRune(text[from]) {
+			from++
+		}
+		for to > from && !isWordRune(text[to-1]) {
+			to--
+		}
+		for k := i; k < j; k++ {
+			r.Words[k].CharStart, r.Words[k].CharEnd = from, to
+		}
+		i = j
+	}
+	return r
+}
+
+// indexRunes returns the index of sub in s starting between from and to, or -1. The match must start and
+// end on word boundaries, so "ten" is not found in "tents".
+func indexRunes(s, sub []rune, from, to int) int {
+	for i := from; i <= to && i+len(sub) <= len(s); i++ {
+		k := 0
+		for k < len(sub) && s[i+k] == sub[k] {
+			k++
+		}
+		if k < len(sub) {
+			continue
+		}
+		if i > 0 && isSpacedRune(s[i-1]) && isSpacedRune(sub[0]) {
+			continue
+		}
+		if end := i + len(sub); end < len(s) && isSpacedRune(s[end]) && isSpacedRune(sub[len(sub)-1]) {
+			continue
+		}
+		return i
+	}
+	return -1
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsNumber(r)
+}
+
+// isSpacedRune reports the word runes of scripts that separate words with spaces, a word of Chinese,
+// Japanese or Thai may start or end next to any other character.
+func isSpacedRune(r rune) bool {
+	return isWordRune(r) && !unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Thai, unicode.Lao, unicode.Khmer, unicode.Myanmar)
+}
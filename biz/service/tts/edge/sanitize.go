@@ -0,0 +1,253 @@
+package edge
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// SanitizeKeep sends the characters to the service as they are
+	SanitizeKeep = "keep"
+	// SanitizeStrip removes the characters
+	SanitizeStrip = "strip"
+	// SanitizeReplace replaces emoji and symbols with their names and control characters with a space
+	SanitizeReplace = "replace"
+)
+
+const (
+	SanitizeCategoryControl   = "control"
+	SanitizeCategoryEmoji     = "emoji"
+	SanitizeCategoryZeroWidth = "zero-width"
+	SanitizeCategorySymbol    = "symbol"
+	// SanitizeCategoryReserved is the private use characters U+E000 to U+E0FF that mark pauses internally,
+	// they are always stripped from the input whatever the policy
+	SanitizeCategoryReserved = "reserved"
+)
+
+// SanitizePolicy tells what happens to each category of characters the service can not read well.
+type SanitizePolicy struct {
+	// Control characters such as U+0007, they are replaced with a space by default
+	Control string
+	// Emoji, including flags, skin tones and joined sequences, kept by default
+	Emoji string
+	// ZeroWidth characters such as U+200B, U+FEFF and bidi marks, kept by default. Replace strips them.
+	ZeroWidth string
+	// Symbols such as ★ ♪ ™, kept by default
+	Symbols string
+	// Names overrides the names used by SanitizeReplace, by default they are picked by the voice locale
+	Names map[string]string
+}
+
+// DefaultSanitizePolicy only replaces control characters, it is what the service has always received.
+func DefaultSanitizePolicy() SanitizePolicy {
+	return SanitizePolicy{
+		Control:   SanitizeReplace,
+		Emoji:     SanitizeKeep,
+		ZeroWidth: SanitizeKeep,
+		Symbols:   SanitizeKeep,
+	}
+}
+
+// SanitizeChange records one change made to the input text, Offset and Length are in bytes of the original text.
+type SanitizeChange struct {
+	Offset      int
+	Length      int
+	Original    string
+	Replacement string
+	Category    string
+}
+
+func validateSanitizePolicy(policy SanitizePolicy) error {
+	for _, action := range []string{policy.Control, policy.Emoji, policy.ZeroWidth, policy.Symbols} {
+		if action != "" && action != SanitizeKeep && action != SanitizeStrip && action != SanitizeReplace {
+			return errors.New("invalid sanitize action " + action)
+		}
+	}
+	return nil
+}
+
+var defaultSanitizeNames = map[string]map[string]string{
+	"en": {
+		"😀": "grinning face", "😂": "face with tears of joy", "🤣": "rolling on the floor laughing", "😊": "smiling face with smiling eyes",
+		"😍": "smiling face with heart-eyes", "😘": "face blowing a kiss", "😉": "winking face", "😎": "smiling face with sunglasses",
+		"🤔": "thinking face", "😢": "crying face", "😭": "loudly crying face", "😡": "pouting face", "😱": "face screaming in fear",
+		"🙏": "folded hands", "👍": "thumbs up", "👎": "thumbs down", "👏": "clapping hands", "👋": "waving hand", "💪": "flexed biceps",
+		"❤": "red heart", "💔": "broken heart", "🔥": "fire", "⭐": "star", "✨": "sparkles", "🎉": "party popper", "🎂": "birthday cake",
+		"🎁": "wrapped gift", "✅": "check mark button", "❌": "cross mark", "⚠": "warning", "💯": "hundred points", "🚀": "rocket",
+		"☀": "sun", "🌧": "cloud with rain", "❄": "snowflake", "🌈": "rainbow", "🐶": "dog face", "🐱": "cat face", "🍎": "red apple",
+		"☕": "hot beverage", "🍺": "beer mug", "📈": "chart increasing", "📉": "chart decreasing", "💰": "money bag", "📞": "telephone receiver",
+		"★": "star", "☆": "star", "♪": "musical note", "♫": "musical notes", "™": "trademark", "©": "copyright", "®": "registered",
+		"✓": "check", "✔": "check", "✗": "cross",
+	},
+	"zh": {
+		"😀": "笑脸", "😂": "笑哭", "🤣": "笑得打滚", "😊": "微笑", "😍": "花痴", "😘": "飞吻", "😉": "眨眼", "😎": "墨镜笑脸",
+		"🤔": "思考", "😢": "哭泣", "😭": "大哭", "😡": "生气", "😱": "惊恐", "🙏": "双手合十", "👍": "点赞", "👎": "踩",
+		"👏": "鼓掌", "👋": "挥手", "💪": "加油", "❤": "红心", "💔": "心碎", "🔥": "火", "⭐": "星星", "✨": "闪亮", "🎉": "庆祝",
+		"🎂": "生日蛋糕", "🎁": "礼物", "✅": "对勾", "❌": "叉号", "⚠": "警告", "💯": "一百分", "🚀": "火箭", "☀": "太阳",
+		"🌧": "下雨", "❄": "雪花", "🌈": "彩虹", "🐶": "小狗", "🐱": "小猫", "🍎": "苹果", "☕": "咖啡", "🍺": "啤酒",
+		"📈": "上涨", "📉": "下跌", "💰": "钱袋", "📞": "电话",
+		"★": "星", "☆": "星", "♪": "音符", "♫": "音符", "™": "商标", "©": "版权", "®": "注册商标",
+		"✓": "对", "✔": "对", "✗": "错",
+	},
+}
+
+// isControl reports the control characters the service has always received as spaces, DEL and the C1
+// controls are not among them.
+func isControl(r rune) bool {
+	return (0 <= r && r <= 8) || (11 <= r && r <= 12) || (14 <= r && r <= 31)
+}
+
+func isZeroWidth(r rune) bool {
+	switch {
+	case r == 0x00AD, r == 0x200B, r == 0x200C, r == 0x200D, r == 0x200E, r == 0x200F, r == 0x2060, r == 0xFEFF:
+		return true
+	case 0x202A <= r && r <= 0x202E, 0x2066 <= r && r <= 0x2069:
+		return true
+	}
+	return false
+}
+
+// emojiPresentation lists the characters of the symbol blocks U+2600 to U+27BF that are drawn as emoji
+// by default, the others are only emoji when followed by U+FE0F.
+const emojiPresentation = "☔☕♈♉♊♋♌♍♎♏♐♑♒♓♿⚓⚡⚪⚫⚽⚾⛄⛅⛎⛔⛪⛲⛳⛵⛺⛽✅✊✋✨❌❎❓❔❕❗➕➖➗➰➿⭐⭕"
+
+// isEmojiAt reports whether text starts with an emoji.
+func isEmojiAt(text string) bool {
+	r, size := utf8.DecodeRuneInString(text)
+	switch {
+	case 0x1F000 <= r && r <= 0x1FAFF:
+		return true
+	case 0x2600 <= r && r <= 0x27BF, 0x2B00 <= r && r <= 0x2BFF, r == 0x231A, r == 0x231B, r == 0x23F0, r == 0x23F3:
+		if strings.ContainsRune(emojiPresentation, r) {
+			return true
+		}
+		next, _ := utf8.DecodeRuneInString(text[size:])
+		return next == 0xFE0F
+	}
+	return false
+}
+
+// isEmojiModifier reports characters that only modify the emoji before them.
+func isEmojiModifier(r rune) bool {
+	return r == 0xFE0F || r == 0xFE0E || r == 0x20E3 || (0x1F3FB <= r && r <= 0x1F3FF) || (0xE0020 <= r && r <= 0xE007F)
+}
+
+func isRegionalIndicator(r rune) bool {
+	return 0x1F1E6 <= r && r <= 0x1F1FF
+}
+
+// emojiCluster returns the byte length of the emoji sequence at the start of text: the emoji with its
+// variation selectors and skin tones, emoji joined by U+200D, and flags made of two regional indicators.
+func emojiCluster(text string) int {
+	r, size := utf8.DecodeRuneInString(text)
+	if isRegionalIndicator(r) {
+		if next, nsize := utf8.DecodeRuneInString(text[size:]); isRegionalIndicator(next) {
+			return size + nsize
+		}
+		return size
+	}
+	for size < len(text) {
+		next, nsize := utf8.DecodeRuneInString(text[size:])
+		switch {
+		case isEmojiModifier(next):
+			size += nsize
+		case next == 0x200D:
+			if isEmojiAt(text[size+nsize:]) {
+				_, asize := utf8.DecodeRuneInString(text[size+nsize:])
+				size += nsize + asize
+			} else {
+				return size
+			}
+		default:
+			return size
+		}
+	}
+	return size
+}
+
+// Sanitize applies the policy to text and returns the sanitized text with every change it made.
+func Sanitize(text string, policy SanitizePolicy, locale string) (string, []SanitizeChange) {
+	names := policy.Names
+	if names == nil {
+		names = defaultSanitizeNames[strings.SplitN(locale, "-", 2)[0]]
+		if names == nil {
+			names = defaultSanitizeNames["en"]
+		}
+	}
+	name := func(s string) string {
+		if n, ok := names[s]; ok {
+			return n
+		}
+		// try without variation selectors and skin tones
+		return names[strings.Map(func(r rune) rune {
+			if isEmojiModifier(r) {
+				return -1
+			}
+			return r
+		}, s)]
+	}
+
+	var b strings.Builder
+	var changes []SanitizeChange
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		category, action := "", SanitizeKeep
+		switch {
+		case isPause(r):
+			category, action = SanitizeCategoryReserved, SanitizeStrip
+		case isControl(r):
+			category, action = SanitizeCategoryControl, policy.Control
+			if action == "" {
+				// replaced by default, as the other categories are kept by default
+				action = SanitizeReplace
+			}
+		case isEmojiAt(text[i:]) || isRegionalIndicator(r):
+			category, action = SanitizeCategoryEmoji, policy.Emoji
+			size = emojiCluster(text[i:])
+		case isZeroWidth(r):
+			category, action = SanitizeCategoryZeroWidth, policy.ZeroWidth
+		case unicode.Is(unicode.So, r):
+			category, action = SanitizeCategorySymbol, policy.Symbols
+		}
+
+		original := text[i : i+size]
+		replacement := original
+		switch action {
+		case SanitizeStrip:
+			replacement = ""
+		case SanitizeReplace:
+			switch category {
+			case SanitizeCategoryControl:
+				replacement = " "
+			case SanitizeCategoryZeroWidth:
+				replacement = ""
+			default:
+				if n := name(original); n != "" {
+					replacement = " " + n + " "
+				} else {
+					replacement = ""
+				}
+			}
+		}
+		if replacement != original {
+			changes = append(changes, SanitizeChange{
+				Offset:      i,
+				Length:      size,
+				Original:    original,
+				Replacement: replacement,
+				Category:    category,
+			})
+		}
+		b.WriteString(replacement)
+		i += size
+	}
+	return b.String(), changes
+}
+
+// SanitizeReport returns every change the sanitize policy makes to the text, that is what is not spoken as written.
+func (c *Communicate) SanitizeReport() []SanitizeChange {
+	_, changes := Sanitize(c.Text, c.SanitizePolicy, voiceLocale(c.VoiceLangRegion))
+	return changes
+}
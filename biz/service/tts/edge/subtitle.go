@@ -0,0 +1,260 @@
+package edge
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	SubtitleSRT    = "srt"
+	SubtitleWebVTT = "vtt"
+	// SubtitleLRC is enhanced LRC with the time of every word
+	SubtitleLRC = "lrc"
+	// SubtitleReadAlong is the json document of ReadAlong, it needs the text so SubMaker can not make it
+	SubtitleReadAlong = "readalong.json"
+)
+
+// SubtitleOptions limits the cues of a SubMaker. Characters are counted in columns, CJK characters
+// count as two, so the same limits suit text with and without spaces.
+type SubtitleOptions struct {
+	// MaxLineChars is the width of a line, 42 by default
+	MaxLineChars int
+	// MaxLines is the number of lines of a cue, 2 by default
+	MaxLines int
+	// MaxDuration is the longest a cue is shown, 7s by default
+	MaxDuration time.Duration
+}
+
+func (o SubtitleOptions) withDefaults() SubtitleOptions {
+	if o.MaxLineChars <= 0 {
+		o.MaxLineChars = 42
+	}
+	if o.MaxLines <= 0 {
+		o.MaxLines = 2
+	}
+	if o.MaxDuration <= 0 {
+		o.MaxDuration = 7 * time.Second
+	}
+	return o
+}
+
+// Cue is one subtitle, its lines are joined with a newline.
+type Cue struct {
+	Start time.Duration
+	End   time.Duration
+	Text  string
+}
+
+// SubMaker groups the word boundaries of a Stream into subtitle cues. Feed it every boundary,
+// a sentence boundary always starts a new cue.
+type SubMaker struct {
+	SubtitleOptions
+	boundaries []Boundary
+}
+
+func NewSubMaker(opts SubtitleOptions) *SubMaker {
+	return &SubMaker{SubtitleOptions: opts}
+}
+
+func (m *SubMaker) Feed(b Boundary) {
+	m.boundaries = append(m.boundaries, b)
+}
+
+// Cues returns the cues of the boundaries fed so far, in time order.
+func (m *SubMaker) Cues() []Cue {
+	opts := m.SubtitleOptions.withDefaults()
+	var cues []Cue
+	for _, words := range m.cueWords(opts) {
+		last := words[len(words)-1]
+		cues = append(cues, Cue{
+			Start: words[0].Offset,
+			End:   last.Offset + last.Duration,
+			Text:  strings.Join(breakLines(words, opts.MaxLineChars), "\n"),
+		})
+	}
+	return cues
+}
+
+// cueWords groups the words fed so far into cues.
+func (m *SubMaker) cueWords(opts SubtitleOptions) [][]Boundary {
+	maxWidth := opts.MaxLineChars * opts.MaxLines
+
+	var sentenceStarts []time.Duration
+	for _, b := range m.boundaries {
+		if b.Type == BoundarySentence {
+			sentenceStarts = append(sentenceStarts, b.Offset)
+		}
+	}
+	startsSentence := func(prev, b Boundary) bool {
+		for _, start := range sentenceStarts {
+			if prev.Offset < start && start <= b.Offset {
+				return true
+			}
+		}
+		return false
+	}
+
+	var cues [][]Boundary
+	var words []Boundary
+	for _, b := range m.boundaries {
+		if b.Type == BoundarySentence || strings.TrimSpace(b.Text) == "" {
+			continue
+		}
+		if len(words) > 0 {
+			prev := words[len(words)-1]
+			if startsSentence(prev, b) || endsSentence(prev.Text) ||
+				textWidth(joinWords(append(words[:len(words):len(words)], b))) > maxWidth ||
+				b.Offset+b.Duration-words[0].Offset > opts.MaxDuration {
+				cues = append(cues, words)
+				words = nil
+			}
+		}
+		words = append(words, b)
+	}
+	if len(words) > 0 {
+		cues = append(cues, words)
+	}
+	return cues
+}
+
+// SRT returns the cues as a SubRip document.
+func (m *SubMaker) SRT() string {
+	var b strings.Builder
+	for i, cue := range m.Cues() {
+		fmt.Fprintf(&b, "%d\n%s --> %s\n%s\n\n", i+1, subtitleTime(cue.Start, ","), subtitleTime(cue.End, ","), cue.Text)
+	}
+	return b.String()
+}
+
+// WebVTT returns the cues as a WebVTT document.
+func (m *SubMaker) WebVTT() string {
+	var b strings.Builder
+	b.WriteString("WEBVTT\n\n")
+	for _, cue := range m.Cues() {
+		fmt.Fprintf(&b, "%s --> %s\n%s\n\n", subtitleTime(cue.Start, "."), subtitleTime(cue.End, "."), vttEscaper.Replace(cue.Text))
+	}
+	return b.String()
+}
+
+// vttEscaper escapes the characters that start tags and entities in a cue, "-->" can not appear once ">" is escaped.
+var vttEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// LRC returns the cues as enhanced LRC, one line per cue with the start time of every word:
+//
+//	[00:01.20]<00:01.20>Hello <00:01.65>world
+//
+// A last line with the end time of the last word clears the lyrics.
+func (m *SubMaker) LRC() string {
+	opts := m.SubtitleOptions.withDefaults()
+	opts.MaxLines = 1
+	var b strings.Builder
+	var end time.Duration
+	for _, words := range m.cueWords(opts) {
+		fmt.Fprintf(&b, "[%s]", lrcTime(words[0].Offset))
+		for i, w := range words {
+			if i > 0 {
+				b.WriteString(wordSeparator(words[i-1].Text, w.Text))
+			}
+			fmt.Fprintf(&b, "<%s>%s", lrcTime(w.Offset), strings.TrimSpace(w.Text))
+			end = w.Offset + w.Duration
+		}
+		b.WriteByte('\n')
+	}
+	if end > 0 {
+		fmt.Fprintf(&b, "[%s]\n", lrcTime(end))
+	}
+	return b.String()
+}
+
+// Subtitle returns the document of a format, SubtitleSRT, SubtitleWebVTT or SubtitleLRC.
+func (m *SubMaker) Subtitle(format string) (string, error) {
+	switch format {
+	case SubtitleSRT:
+		return m.SRT(), nil
+	case SubtitleWebVTT:
+		return m.WebVTT(), nil
+	case SubtitleLRC:
+		return m.LRC(), nil
+	}
+	return "", fmt.Errorf("unknown subtitle format %s", format)
+}
+
+func subtitleTime(d time.Duration, sep string) string {
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d%s%03d", ms/3600000, ms/60000%60, ms/1000%60, sep, ms%1000)
+}
+
+// lrcTime formats mm:ss.xx, minutes go past 59 as LRC has no hours.
+func lrcTime(d time.Duration) string {
+	cs := d.Milliseconds() / 10
+	return fmt.Sprintf("%02d:%02d.%02d", cs/6000, cs/100%60, cs%100)
+}
+
+// breakLines joins words into lines no wider than width, a word wider than a line gets its own line.
+func breakLines(words []Boundary, width int) []string {
+	var lines []string
+	var line []Boundary
+	for _, w := range words {
+		if len(line) > 0 && textWidth(joinWords(append(line[:len(line):len(line)], w))) > width {
+			lines = append(lines, joinWords(line))
+			line = nil
+		}
+		line = append(line, w)
+	}
+	if len(line) > 0 {
+		lines = append(lines, joinWords(line))
+	}
+	return lines
+}
+
+// joinWords joins words with a space, except between CJK characters and before punctuation.
+func joinWords(words []Boundary) string {
+	var b strings.Builder
+	for i, w := range words {
+		if i > 0 {
+			b.WriteString(wordSeparator(words[i-1].Text, w.Text))
+		}
+		b.WriteString(strings.TrimSpace(w.Text))
+	}
+	return b.String()
+}
+
+func wordSeparator(prev, next string) string {
+	p, _ := utf8.DecodeLastRuneInString(strings.TrimSpace(prev))
+	n, _ := utf8.DecodeRuneInString(strings.TrimSpace(next))
+	if (unspaced(p) && unspaced(n)) || unicode.IsPunct(n) {
+		return ""
+	}
+	return " "
+}
+
+func textWidth(s string) int {
+	width := 0
+	for _, r := range s {
+		if isWide(r) {
+			width += 2
+		} else {
+			width++
+		}
+	}
+	return width
+}
+
+// isWide reports CJK characters and full-width punctuation, they take two columns.
+func isWide(r rune) bool {
+	return unspaced(r) || unicode.Is(unicode.Hangul, r)
+}
+
+// unspaced reports characters written without spaces between words, Korean has spaces.
+func unspaced(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana) ||
+		(0x3000 <= r && r <= 0x303F) || (0xFF00 <= r && r <= 0xFF60)
+}
+
+func endsSentence(text string) bool {
+	r, _ := utf8.DecodeLastRuneInString(strings.TrimSpace(text))
+	return strings.ContainsRune(".!?。！？", r)
+}
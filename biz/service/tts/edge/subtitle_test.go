@@ -0,0 +1,97 @@
+package edge
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func words(texts ...string) []Boundary {
+	var bs []Boundary
+	for i, text := range texts {
+		bs = append(bs, Boundary{Type: BoundaryWord, Offset: time.Duration(i) * 500 * time.Millisecond, Duration: 400 * time.Millisecond, Text: text})
+	}
+	return bs
+}
+
+func TestSubMaker(t *testing.T) {
+	m := NewSubMaker(SubtitleOptions{MaxLineChars: 12})
+	for _, b := range words("Hello", "world.", "This", "is", "a", "longer", "sentence", "of", "words") {
+		m.Feed(b)
+	}
+	want := "1\n00:00:00,000 --> 00:00:00,900\nHello world.\n\n" +
+		"2\n00:00:01,000 --> 00:00:02,900\nThis is a\nlonger\n\n" +
+		"3\n00:00:03,000 --> 00:00:04,400\nsentence of\nwords\n\n"
+	if got := m.SRT(); got != want {
+		t.Errorf("SRT = %q, want %q", got, want)
+	}
+	if vtt := m.WebVTT(); !strings.HasPrefix(vtt, "WEBVTT\n\n00:00:00.000 --> 00:00:00.900\nHello world.\n\n") {
+		t.Errorf("WebVTT = %q", vtt)
+	}
+	m = NewSubMaker(SubtitleOptions{})
+	for _, b := range words("A", "&", "B", "-->", "<c>") {
+		m.Feed(b)
+	}
+	if vtt := m.WebVTT(); vtt != "WEBVTT\n\n00:00:00.000 --> 00:00:02.400\nA&amp; B--&gt; &lt;c&gt;\n\n" {
+		t.Errorf("WebVTT = %q", vtt)
+	}
+
+	// CJK words are joined without spaces and count two columns
+	m = NewSubMaker(SubtitleOptions{MaxLineChars: 8, MaxLines: 1})
+	for _, b := range words("我们", "今天", "去", "公园", "玩") {
+		m.Feed(b)
+	}
+	cues := m.Cues()
+	if len(cues) != 2 || cues[0].Text != "我们今天" || cues[1].Text != "去公园玩" {
+		t.Errorf("cues = %+v", cues)
+	}
+
+	// a sentence boundary starts a cue, so does the duration limit
+	m = NewSubMaker(SubtitleOptions{MaxDuration: 1500 * time.Millisecond})
+	for _, b := range words("one", "two", "three", "four", "five", "six") {
+		m.Feed(b)
+	}
+	m.Feed(Boundary{Type: BoundarySentence, Offset: time.Second, Text: "three four five six"})
+	cues = m.Cues()
+	if len(cues) != 3 || cues[0].Text != "one two" || cues[1].Text != "three four five" || cues[2].Text != "six" {
+		t.Errorf("cues = %+v", cues)
+	}
+}
+
+func TestLRC(t *testing.T) {
+	m := NewSubMaker(SubtitleOptions{})
+	for _, b := range words("你好", "世界。", "Hello", "world") {
+		m.Feed(b)
+	}
+	want := "[00:00.00]<00:00.00>你好<00:00.50>世界。\n[00:01.00]<00:01.00>Hello <00:01.50>world\n[00:01.90]\n"
+	if got := m.LRC(); got != want {
+		t.Errorf("LRC = %q, want %q", got, want)
+	}
+}
+
+func TestReadAlong(t *testing.T) {
+	c, err := NewCommunicate("I have 23 cats &\u0007 dogs.", WithVoice("en-US-AriaNeural"))
+	if err != nil {
+		t.Fatalf("NewCommunicate fail, err: %v", err)
+	}
+	r := c.ReadAlong(words("I", "have", "twenty", "three", "cats", "&amp;", "Dogs"))
+	want := [][2]int{{0, 1}, {2, 6}, {7, 9}, {7, 9}, {10, 14}, {15, 16}, {18, 22}}
+	for i, w := range r.Words {
+		if w.CharStart != want[i][0] || w.CharEnd != want[i][1] {
+			t.Errorf("word %q range [%d, %d), want %v", w.Text, w.CharStart, w.CharEnd, want[i])
+		}
+	}
+	if r.Words[1].Start != 500 || r.Words[1].End != 900 {
+		t.Errorf("word times = %+v", r.Words[1])
+	}
+
+	// a word is not matched inside a longer one
+	c, _ = NewCommunicate("10 tents, 你好世界", WithVoice("en-US-AriaNeural"))
+	r = c.ReadAlong(words("ten", "tents", "你好", "世界"))
+	want = [][2]int{{0, 2}, {3, 8}, {10, 12}, {12, 14}}
+	for i, w := range r.Words {
+		if w.CharStart != want[i][0] || w.CharEnd != want[i][1] {
+			t.Errorf("word %q range [%d, %d), want %v", w.Text, w.CharStart, w.CharEnd, want[i])
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package edge
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// VoiceInfo describes what a voice supports beyond plain synthesis.
+// https://learn.microsoft.com/en-us/azure/cognitive-services/speech-service/language-support?tabs=tts#voice-styles-and-roles
+type VoiceInfo struct {
+	// ShortName is the voice name as passed to WithVoice, e.g. zh-CN-XiaoxiaoNeural
+	ShortName string
+	Styles    []string
+	Roles     []string
+	// Multilingual voices can speak other languages inside <lang xml:lang>
+	Multilingual bool
+}
+
+var (
+	voiceCatalogMu sync.RWMutex
+	voiceCatalog   = map[string]VoiceInfo{}
+)
+
+func init() {
+	roles := []string{"Boy", "Girl", "OlderAdultFemale", "OlderAdultMale", "SeniorFemale", "SeniorMale", "YoungAdultFemale", "YoungAdultMale"}
+	for _, v := range []VoiceInfo{
+		{ShortName: "zh-CN-XiaoxiaoNeural", Styles: []string{"affectionate", "angry", "assistant", "calm", "chat", "chat-casual", "cheerful", "customerservice", "disgruntled", "fearful", "friendly", "gentle", "lyrical", "newscast", "poetry-reading", "sad", "serious", "sorry", "whisper"}},
+		{ShortName: "zh-CN-XiaoyiNeural", Styles: []string{"affectionate", "angry", "cheerful", "disgruntled", "embarrassed", "fearful", "gentle", "sad", "serious"}},
+		{ShortName: "zh-CN-XiaomoNeural", Styles: []string{"affectionate", "angry", "calm", "cheerful", "depressed", "disgruntled", "embarrassed", "envious", "fearful", "gentle", "sad", "serious"}, Roles: roles},
+		{ShortName: "zh-CN-XiaoxuanNeural", Styles: []string{"angry", "calm", "cheerful", "depressed", "disgruntled", "fearful", "gentle", "serious"}, Roles: roles},
+		{ShortName: "zh-CN-YunxiNeural", Styles: []string{"angry", "assistant", "chat", "cheerful", "depressed", "disgruntled", "embarrassed", "fearful", "narration-relaxed", "newscast", "sad", "serious"}, Roles: []string{"Boy", "Narrator", "YoungAdultMale"}},
+		{ShortName: "zh-CN-YunyangNeural", Styles: []string{"customerservice", "narration-professional", "newscast-casual"}},
+		{ShortName: "zh-CN-YunjianNeural", Styles: []string{"angry", "cheerful", "depressed", "disgruntled", "documentary-narration", "narration-relaxed", "sad", "serious", "sports-commentary", "sports-commentary-excited"}},
+		{ShortName: "en-US-AriaNeural", Styles: []string{"angry", "chat", "cheerful", "customerservice", "empathetic", "excited", "friendly", "hopeful", "narration-professional", "newscast-casual", "newscast-formal", "sad", "shouting", "terrified", "unfriendly", "whispering"}},
+		{ShortName: "en-US-JennyNeural", Styles: []string{"angry", "assistant", "chat", "cheerful", "customerservice", "excited", "friendly", "hopeful", "newscast", "sad", "shouting", "terrified", "unfriendly", "whispering"}},
+		{ShortName: "en-US-GuyNeural", Styles: []string{"angry", "cheerful", "excited", "friendly", "hopeful", "newscast", "sad", "shouting", "terrified", "unfriendly", "whispering"}},
+		{ShortName: "en-US-DavisNeural", Styles: []string{"angry", "chat", "cheerful", "excited", "friendly", "hopeful", "sad", "shouting", "terrified", "unfriendly", "whispering"}},
+		{ShortName: "en-US-SaraNeural", Styles: []string{"angry", "cheerful", "excited", "friendly", "hopeful", "sad", "shouting", "terrified", "unfriendly", "whispering"}},
+		{ShortName: "ja-JP-NanamiNeural", Styles: []string{"chat", "cheerful", "customerservice"}},
+		{ShortName: "en-US-JennyMultilingualNeural", Multilingual: true},
+		{ShortName: "en-US-RyanMultilingualNeural", Multilingual: true},
+		{ShortName: "en-US-AvaMultilingualNeural", Multilingual: true},
+		{ShortName: "en-US-AndrewMultilingualNeural", Multilingual: true},
+		{ShortName: "en-US-EmmaMultilingualNeural", Multilingual: true},
+		{ShortName: "en-US-BrianMultilingualNeural", Multilingual: true},
+	} {
+		RegisterVoice(v)
+	}
+}
+
+// RegisterVoice adds or replaces a voice in the catalog used to validate styles and roles.
+func RegisterVoice(info VoiceInfo) {
+	voiceCatalogMu.Lock()
+	defer voiceCatalogMu.Unlock()
+	voiceCatalog[info.ShortName] = info
+}
+
+// LookupVoice returns the catalog entry of a voice by its short name.
+func LookupVoice(shortName string) (VoiceInfo, bool) {
+	voiceCatalogMu.RLock()
+	defer voiceCatalogMu.RUnlock()
+	info, ok := voiceCatalog[shortName]
+	return info, ok
+}
+
+var validVoicePattern = regexp.MustCompile(`^([a-z]{2,})-([A-Z]{2,})-(.+Neural)$`)
+
+// voiceName returns the full name the service expects for a voice short name.
+func voiceName(shortName string) (string, error) {
+	if !validVoicePattern.MatchString(shortName) {
+		return "", errors.New("invalid voice")
+	}
+	strs := strings.SplitN(shortName, "-", 3)
+	return fmt.Sprintf("Microsoft Server Speech Text to Speech Voice (%s-%s, %s)", strs[0], strs[1], strs[2]), nil
+}
+
+// isMultilingual reports whether the voice is marked multilingual in the catalog or by its name.
+func isMultilingual(shortName string) bool {
+	if info, ok := LookupVoice(shortName); ok && info.Multilingual {
+		return true
+	}
+	return strings.Contains(shortName, "Multilingual")
+}
+
+// voiceLocale returns the locale part of a voice short name, zh-CN-XiaoxiaoNeural -> zh-CN
+func voiceLocale(shortName string) string {
+	strs := strings.SplitN(shortName, "-", 3)
+	if len(strs) < 2 {
+		return ""
+	}
+	return strs[0] + "-" + strs[1]
+}
+
+func (info VoiceInfo) supportsStyle(style string) bool {
+	for _, s := range info.Styles {
+		if strings.EqualFold(s, style) {
+			return true
+		}
+	}
+	return false
+}
+
+func (info VoiceInfo) supportsRole(role string) bool {
+	for _, r := range info.Roles {
+		if strings.EqualFold(r, role) {
+			return true
+		}
+	}
+	return false
+}
+
+func validateExpressAs(shortName, style, styleDegree, role string) error {
+	if style == "" {
+		if styleDegree != "" || role != "" {
+			return errors.New("style degree and role require a style")
+		}
+		return nil
+	}
+
+	info, ok := LookupVoice(shortName)
+	if !ok {
+		return fmt.Errorf("voice %s has no styles in the voice catalog", shortName)
+	}
+	if !info.supportsStyle(style) {
+		return fmt.Errorf("style %s is not supported by voice %s", style, shortName)
+	}
+	if role != "" && !info.supportsRole(role) {
+		return fmt.Errorf("role %s is not supported by voice %s", role, shortName)
+	}
+	if styleDegree != "" {
+		degree, err := strconv.ParseFloat(styleDegree, 64)
+		if err != nil || degree < 0.01 || degree > 2 {
+			return errors.New("invalid style degree, must be within 0.01 to 2")
+		}
+	}
+	return nil
+}
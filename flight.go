@@ -0,0 +1,87 @@
+package edge_tts_go
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"path/filepath"
+	"sync"
+
+	storage "github.com/pp-group/file-helper/storage"
+)
+
+// synthesisFlights coalesces the concurrent syntheses of one object in this process.
+var synthesisFlights = &flightGroup{flights: map[string]*flight{}}
+
+type flight struct {
+	done   chan struct{}
+	result *Result
+	err    error
+	// canceled tells that the caller running the flight gave up, so the waiting callers try again
+	canceled bool
+}
+
+// flightGroup runs one function per key at a time, the callers of a key that is running wait for its result.
+type flightGroup struct {
+	mu      sync.Mutex
+	flights map[string]*flight
+}
+
+// do returns the result of fn, or of the running fn of key and true. A caller waits until its own ctx is done.
+func (g *flightGroup) do(ctx context.Context, key string, fn func() (*Result, error)) (*Result, bool, error) {
+	for {
+		g.mu.Lock()
+		if f, ok := g.flights[key]; ok {
+			g.mu.Unlock()
+			select {
+			case <-ctx.Done():
+				return nil, false, ctx.Err()
+			case <-f.done:
+			}
+			if f.canceled {
+				continue
+			}
+			return f.result, true, f.err
+		}
+		f := &flight{done: make(chan struct{})}
+		g.flights[key] = f
+		g.mu.Unlock()
+
+		f.result, f.err = fn()
+		f.canceled = f.err != nil && ctx.Err() != nil
+		g.mu.Lock()
+		delete(g.flights, key)
+		g.mu.Unlock()
+		close(f.done)
+		return f.result, false, f.err
+	}
+}
+
+// flightKey names the object key of a service across the services of this process, so speeches
+// with their own storage clients share the flights of one folder or bucket.
+func flightKey(s *SpeechService, key string, forceRefresh bool) string {
+	var object string
+	switch st := s.storage.(type) {
+	case *storage.FileStorage:
+		p, err := filepath.Abs(filepath.Join(s.folder, key))
+		if err != nil {
+			p = filepath.Join(s.folder, key)
+		}
+		object = "file://" + filepath.ToSlash(p)
+	case *storage.OssStorage:
+		bucket := ""
+		if s.helper != nil {
+			bucket, _ = s.helper().(string)
+		}
+		object = fmt.Sprintf("oss://%s/%s/%s", st.Client.Config.Endpoint, bucket, path.Join(s.folder, key))
+	case *S3Storage:
+		broker := st.broker(key, s.helper)
+		object = st.objectURL(broker.bucket, broker.key).String()
+	default:
+		object = fmt.Sprintf("%p/%s", s.storage, path.Join(s.folder, key))
+	}
+	if forceRefresh {
+		return object + "#refresh"
+	}
+	return object
+}
@@ -0,0 +1,193 @@
+package edge_tts_go
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pp-group/edge-tts-go/biz/service/tts/edge"
+)
+
+// joinContext tells on joined when a caller of flightGroup.do waits on it, that is once it joined a flight.
+type joinContext struct {
+	context.Context
+	once   sync.Once
+	joined chan<- struct{}
+}
+
+func (ctx *joinContext) Done() <-chan struct{} {
+	ctx.once.Do(func() { ctx.joined <- struct{}{} })
+	return ctx.Context.Done()
+}
+
+func TestFlightGroup(t *testing.T) {
+	g := &flightGroup{flights: map[string]*flight{}}
+	release := make(chan struct{})
+	joined := make(chan struct{}, 8)
+	var calls, shared atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx := &joinContext{Context: context.Background(), joined: joined}
+			result, ok, err := g.do(ctx, "key", func() (*Result, error) {
+				calls.Add(1)
+				<-release
+				return &Result{Key: "key"}, nil
+			})
+			if err != nil || result.Key != "key" {
+				t.Errorf("do = %+v, err: %v", result, err)
+			}
+			if ok {
+				shared.Add(1)
+			}
+		}()
+	}
+	// the first caller runs the function once the others wait for it
+	for i := 0; i < 7; i++ {
+		<-joined
+	}
+	close(release)
+	wg.Wait()
+	if calls.Load() != 1 || shared.Load() != 7 {
+		t.Errorf("calls = %d, shared = %d", calls.Load(), shared.Load())
+	}
+
+	// a waiting caller runs the function itself when the first caller gives up
+	ctx, cancel := context.WithCancel(context.Background())
+	started := make(chan struct{})
+	go g.do(ctx, "key", func() (*Result, error) {
+		close(started)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	<-started
+	time.AfterFunc(20*time.Millisecond, cancel)
+	result, ok, err := g.do(context.Background(), "key", func() (*Result, error) {
+		return &Result{Key: "retried"}, nil
+	})
+	if err != nil || ok || result.Key != "retried" {
+		t.Errorf("do after cancel = %+v, %v, err: %v", result, ok, err)
+	}
+}
+
+func TestLease(t *testing.T) {
+	defer func(interval time.Duration) { leasePollInterval = interval }(leasePollInterval)
+	leasePollInterval = 10 * time.Millisecond
+
+	folder := t.TempDir()
+	service, err := NewLocalSpeechService(folder)
+	if err != nil {
+		t.Fatalf("NewLocalSpeechService fail, err: %v", err)
+	}
+	service.Lease = time.Minute
+	req := Request{Text: "leased"}
+	c, _ := edge.NewCommunicate(req.Text)
+	key := Key(c)
+
+	// another process holds the lease and stores the object
+	held, _ := json.Marshal(lease{Holder: "other:1", Expires: time.Now().Add(time.Minute)})
+	if err := os.WriteFile(filepath.Join(folder, LeaseKey(key)), held, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	time.AfterFunc(50*time.Millisecond, func() {
+		os.WriteFile(filepath.Join(folder, key), make([]byte, 6000), 0o644)
+		os.Remove(filepath.Join(folder, LeaseKey(key)))
+	})
+	result, err := service.Synthesize(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Synthesize fail, err: %v", err)
+	}
+	if !result.Cached || result.Size != 6000 {
+		t.Errorf("unexpected result: %+v", result)
+	}
+
+	// an expired lease is taken over
+	expired, _ := json.Marshal(lease{Holder: "other:1", Expires: time.Now().Add(-time.Second)})
+	os.WriteFile(filepath.Join(folder, LeaseKey("expired")), expired, 0o644)
+	mine, err := service.tryLease("expired")
+	if mine == nil || err != nil {
+		t.Fatalf("tryLease of an expired lease = %v, err: %v", mine, err)
+	}
+	if l, err := service.tryLease("expired"); l != nil || err != nil {
+		t.Errorf("tryLease of a held lease = %v, err: %v", l, err)
+	}
+
+	// a lease taken over by another process is not released by its previous holder
+	other, _ := json.Marshal(lease{Holder: "other:2", Expires: time.Now().Add(time.Minute)})
+	os.WriteFile(filepath.Join(folder, LeaseKey("expired")), other, 0o644)
+	service.releaseLease("expired", mine)
+	if data, _ := os.ReadFile(filepath.Join(folder, LeaseKey("expired"))); string(data) != string(other) {
+		t.Errorf("lease of another holder was released: %s", data)
+	}
+	os.Remove(filepath.Join(folder, LeaseKey("expired")))
+	mine, _ = service.tryLease("expired")
+	service.releaseLease("expired", mine)
+	if _, err := os.Stat(filepath.Join(folder, LeaseKey("expired"))); !os.IsNotExist(err) {
+		t.Errorf("own lease should be released, err: %v", err)
+	}
+}
+
+func TestCreateObject(t *testing.T) {
+	fake := &fakeS3{objects: map[string][]byte{}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	s3Storage, err := NewS3Storage(S3Config{Endpoint: server.URL, Bucket: "audio", AccessKey: "minio", SecretKey: "minio123", PathStyle: true}, "tts")
+	if err != nil {
+		t.Fatalf("NewS3Storage fail, err: %v", err)
+	}
+	local, err := NewLocalSpeechService(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocalSpeechService fail, err: %v", err)
+	}
+
+	for _, s := range []*SpeechService{local, NewMemorySpeechService(NewMemoryStorage()), newS3SpeechService(s3Storage)} {
+		for i, want := range []bool{true, false} {
+			ok, err := createObject(s.storage, s.helper, s.folder, "a.lease", []byte{byte(i)})
+			if ok != want || err != nil {
+				t.Errorf("%T createObject #%d = %v, err: %v", s.storage, i, ok, err)
+			}
+		}
+		if err := deleteObject(s.storage, s.helper, s.folder, "a.lease"); err != nil {
+			t.Errorf("%T deleteObject fail, err: %v", s.storage, err)
+		}
+		if ok, err := createObject(s.storage, s.helper, s.folder, "a.lease", nil); !ok || err != nil {
+			t.Errorf("%T createObject after delete = %v, err: %v", s.storage, ok, err)
+		}
+	}
+	// racing creators see either no file or the whole file of the one that won
+	folder := local.folder
+	var created atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			data := []byte(fmt.Sprintf(`{"holder":"p%d"}`, i))
+			ok, err := createObject(local.storage, local.helper, folder, "race.lease", data)
+			if err != nil {
+				t.Errorf("createObject fail, err: %v", err)
+			}
+			if ok {
+				created.Add(1)
+			} else if held, err := os.ReadFile(filepath.Join(folder, "race.lease")); err != nil || len(held) == 0 {
+				t.Errorf("lost the race to a lease of %q, err: %v", held, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if created.Load() != 1 {
+		t.Errorf("%d creators won", created.Load())
+	}
+	if temps, _ := filepath.Glob(filepath.Join(folder, "*"+tempSuffix)); len(temps) != 0 {
+		t.Errorf("temporary files left: %v", temps)
+	}
+}
@@ -0,0 +1,75 @@
+package edge_tts_go
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"unicode/utf16"
+)
+
+// chapterTag builds an ID3v2.3 tag with a table of contents and one CHAP frame per chapter,
+// see https://id3.org/id3v2-chapters-1.0
+func chapterTag(chapters []AudiobookChapter) []byte {
+	var frames bytes.Buffer
+
+	toc := bytes.NewBufferString("toc\x00")
+	// top level and ordered
+	toc.WriteByte(0x03)
+	count := len(chapters)
+	if count > 255 {
+		count = 255
+	}
+	toc.WriteByte(byte(count))
+	for _, chapter := range chapters[:count] {
+		toc.WriteString(chapterElementID(chapter) + "\x00")
+	}
+	writeID3Frame(&frames, "CTOC", toc.Bytes())
+
+	for _, chapter := range chapters {
+		var chap bytes.Buffer
+		chap.WriteString(chapterElementID(chapter) + "\x00")
+		binary.Write(&chap, binary.BigEndian, uint32(chapter.Start*1000))
+		binary.Write(&chap, binary.BigEndian, uint32((chapter.Start+chapter.Duration)*1000))
+		// byte offsets are unknown
+		binary.Write(&chap, binary.BigEndian, uint32(0xFFFFFFFF))
+		binary.Write(&chap, binary.BigEndian, uint32(0xFFFFFFFF))
+		title := chapter.Title
+		if title == "" {
+			title = fmt.Sprintf("%d", chapter.Index)
+		}
+		writeID3Frame(&chap, "TIT2", id3Text(title))
+		writeID3Frame(&frames, "CHAP", chap.Bytes())
+	}
+
+	var tag bytes.Buffer
+	tag.WriteString("ID3")
+	tag.Write([]byte{0x03, 0x00, 0x00})
+	tag.Write(syncsafe(uint32(frames.Len())))
+	tag.Write(frames.Bytes())
+	return tag.Bytes()
+}
+
+func chapterElementID(chapter AudiobookChapter) string {
+	return fmt.Sprintf("chp%d", chapter.Index)
+}
+
+func writeID3Frame(w *bytes.Buffer, id string, body []byte) {
+	w.WriteString(id)
+	binary.Write(w, binary.BigEndian, uint32(len(body)))
+	// no frame flags
+	w.Write([]byte{0x00, 0x00})
+	w.Write(body)
+}
+
+// id3Text encodes a text frame as UTF-16 with BOM, the only unicode encoding of ID3v2.3.
+func id3Text(s string) []byte {
+	buf := []byte{0x01, 0xFF, 0xFE}
+	for _, u := range utf16.Encode([]rune(s)) {
+		buf = append(buf, byte(u), byte(u>>8))
+	}
+	return append(buf, 0x00, 0x00)
+}
+
+func syncsafe(n uint32) []byte {
+	return []byte{byte(n>>21) & 0x7F, byte(n>>14) & 0x7F, byte(n>>7) & 0x7F, byte(n) & 0x7F}
+}
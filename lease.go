@@ -0,0 +1,222 @@
+package edge_tts_go
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/aliyun/aliyun-oss-go-sdk/oss"
+	storage "github.com/pp-group/file-helper/storage"
+)
+
+// leasePollInterval is how often a service waiting for the lease of another process checks it.
+var leasePollInterval = 500 * time.Millisecond
+
+// leaseHolder names this process in its leases.
+var leaseHolder = func() string {
+	host, _ := os.Hostname()
+	return fmt.Sprintf("%s:%d", host, os.Getpid())
+}()
+
+// LeaseKey is the object a service holds while it synthesizes key, see SpeechService.Lease.
+func LeaseKey(key string) string {
+	return key + ".lease"
+}
+
+type lease struct {
+	Holder  string    `json:"holder"`
+	Expires time.Time `json:"expires"`
+}
+
+// acquireLease waits until this process holds the lease of key and returns its release. The holder
+// releases the lease once the object and its metadata are stored, so when the object exists after
+// waiting its size is returned instead, unless forceRefresh.
+func (s *SpeechService) acquireLease(ctx context.Context, key string, forceRefresh bool) (int64, func(), error) {
+	for {
+		held, err := s.tryLease(key)
+		if err != nil {
+			return 0, nil, err
+		}
+		if held != nil {
+			release := func() {
+				s.releaseLease(key, held)
+			}
+			if forceRefresh {
+				return 0, release, nil
+			}
+			// the previous holder may have stored the object just before releasing
+			size, stored, err := s.stat(key)
+			if err != nil || stored {
+				release()
+				return size, nil, err
+			}
+			return 0, release, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return 0, nil, ctx.Err()
+		case <-time.After(leasePollInterval):
+		}
+	}
+}
+
+// tryLease creates the lease of key, or takes it over once it expired, and returns it. It returns nil
+// while another process holds the lease.
+func (s *SpeechService) tryLease(key string) (*lease, error) {
+	mine := &lease{Holder: leaseHolder, Expires: time.Now().Add(s.Lease)}
+	data, err := json.Marshal(mine)
+	if err != nil {
+		return nil, err
+	}
+	ok, err := createObject(s.storage, s.helper, s.folder, LeaseKey(key), data)
+	if err != nil || ok {
+		return leaseIf(mine, ok), err
+	}
+
+	l, err := s.readLease(key)
+	if err != nil {
+		// released since, it is created again on the next try
+		return nil, nil
+	}
+	if l != nil && time.Now().Before(l.Expires) {
+		return nil, nil
+	}
+	// the holder died or is past its lease, two processes taking over at once may both synthesize
+	if err := deleteObject(s.storage, s.helper, s.folder, LeaseKey(key)); err != nil {
+		return nil, err
+	}
+	ok, err = createObject(s.storage, s.helper, s.folder, LeaseKey(key), data)
+	return leaseIf(mine, ok), err
+}
+
+func leaseIf(l *lease, ok bool) *lease {
+	if !ok {
+		return nil
+	}
+	return l
+}
+
+// readLease reads the lease of key, a lease that can not be decoded is nil.
+func (s *SpeechService) readLease(key string) (*lease, error) {
+	data, err := readObject(s.storage, s.helper, s.folder, LeaseKey(key))
+	if err != nil {
+		return nil, err
+	}
+	l := &lease{}
+	if json.Unmarshal(data, l) != nil {
+		return nil, nil
+	}
+	return l, nil
+}
+
+// releaseLease deletes the lease of key unless a holder that ran past it lost it to another process.
+func (s *SpeechService) releaseLease(key string, held *lease) {
+	l, err := s.readLease(key)
+	if err != nil || l == nil || l.Holder != held.Holder || !l.Expires.Equal(held.Expires) {
+		return
+	}
+	deleteObject(s.storage, s.helper, s.folder, LeaseKey(key))
+}
+
+// createObject stores data as key unless key exists, and reports whether it did. Two processes
+// creating one key at once never both succeed.
+func createObject(s storage.IStorage, helper storage.ParamsHelper, folder, key string, data []byte) (bool, error) {
+	switch st := s.(type) {
+	case *storage.FileStorage:
+		path := filepath.Join(folder, key)
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			return false, err
+		}
+		// the whole file is linked into place, so a reader never sees it empty or half written
+		f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*"+tempSuffix)
+		if err != nil {
+			return false, err
+		}
+		defer os.Remove(f.Name())
+		_, err = f.Write(data)
+		if err == nil {
+			err = f.Chmod(objectMode)
+		}
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return false, err
+		}
+		err = os.Link(f.Name(), path)
+		if os.IsExist(err) {
+			return false, nil
+		}
+		return err == nil, err
+	case *storage.OssStorage:
+		if helper == nil {
+			return false, errors.New("oss storage must need a paramsHelper to specify buckert name")
+		}
+		bucket, err := st.Bucket(helper().(string))
+		if err != nil {
+			return false, err
+		}
+		err = bucket.PutObject(path.Join(folder, key), bytes.NewReader(data), oss.ForbidOverWrite(true))
+		var serr oss.ServiceError
+		if errors.As(err, &serr) && serr.StatusCode == http.StatusConflict {
+			return false, nil
+		}
+		return err == nil, err
+	case *S3Storage:
+		broker := st.broker(key, helper)
+		resp, err := st.doWithHeader(http.MethodPut, broker.bucket, broker.key, data, http.Header{"If-None-Match": {"*"}})
+		if err != nil {
+			return false, err
+		}
+		defer resp.Body.Close()
+		switch resp.StatusCode {
+		case http.StatusOK:
+			return true, nil
+		case http.StatusPreconditionFailed, http.StatusConflict:
+			return false, nil
+		}
+		return false, s3Error(resp)
+	case *MemoryStorage:
+		st.mu.Lock()
+		defer st.mu.Unlock()
+		if _, ok := st.objects[key]; ok {
+			return false, nil
+		}
+		st.objects[key] = append([]byte{}, data...)
+		return true, nil
+	}
+	return false, fmt.Errorf("%T can not create objects exclusively", s)
+}
+
+// deleteObject deletes an object, it is not an error if it does not exist.
+func deleteObject(s storage.IStorage, helper storage.ParamsHelper, folder, key string) error {
+	switch st := s.(type) {
+	case *storage.FileStorage:
+		if err := os.Remove(filepath.Join(folder, key)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	case *storage.OssStorage:
+		if helper == nil {
+			return errors.New("oss storage must need a paramsHelper to specify buckert name")
+		}
+		bucket, err := st.Bucket(helper().(string))
+		if err != nil {
+			return err
+		}
+		return bucket.DeleteObject(path.Join(folder, key))
+	}
+	broker, err := s.Manager(key, helper)
+	if err != nil {
+		return err
+	}
+	return broker.Delete(key)
+}
@@ -0,0 +1,169 @@
+package edge_tts_go
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+
+	storage "github.com/pp-group/file-helper/storage"
+)
+
+// tempSuffix ends the names of temporary files, cache lookups never match them.
+const tempSuffix = ".tmp"
+
+// objectMode is the mode of stored files, os.CreateTemp makes files only their owner can read
+// and a gateway serving them may run as another user.
+const objectMode = 0o644
+
+var _ storage.IWriteBroker = new(AtomicFileBroker)
+
+// AtomicFileBroker writes a temporary file next to the object and renames it over the object on Close,
+// so a reader sees either the old object or the whole new one, never a partial file.
+type AtomicFileBroker struct {
+	file *os.File
+	path string
+	done bool
+}
+
+func NewAtomicFileBroker(folder, fileName string) (*AtomicFileBroker, error) {
+	path := filepath.Join(folder, fileName)
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return nil, err
+	}
+	file, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*"+tempSuffix)
+	if err != nil {
+		return nil, err
+	}
+	return &AtomicFileBroker{file: file, path: path}, nil
+}
+
+func (broker *AtomicFileBroker) Write(p []byte) (int, error) {
+	if broker.done {
+		return 0, os.ErrClosed
+	}
+	return broker.file.Write(p)
+}
+
+// WriteAt rewrites what was written, such as the mp3 Xing frame.
+func (broker *AtomicFileBroker) WriteAt(p []byte, off int64) (int, error) {
+	if broker.done {
+		return 0, os.ErrClosed
+	}
+	return broker.file.WriteAt(p, off)
+}
+
+// Close syncs the temporary file and renames it to the object, the temporary file is removed on failure.
+func (broker *AtomicFileBroker) Close() error {
+	if broker.done {
+		return nil
+	}
+	broker.done = true
+	err := broker.file.Chmod(objectMode)
+	if err == nil {
+		err = broker.file.Sync()
+	}
+	if closeErr := broker.file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(broker.file.Name(), broker.path)
+	}
+	if err != nil {
+		os.Remove(broker.file.Name())
+	}
+	return err
+}
+
+// Abort removes the temporary file and keeps the object as it was.
+func (broker *AtomicFileBroker) Abort() error {
+	if broker.done {
+		return nil
+	}
+	broker.done = true
+	broker.file.Close()
+	return os.Remove(broker.file.Name())
+}
+
+func (broker *AtomicFileBroker) Exist() (bool, error) {
+	_, err := os.Stat(broker.path)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (broker *AtomicFileBroker) URL() (string, error) {
+	ok, err := broker.Exist()
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", os.ErrNotExist
+	}
+	return broker.path, nil
+}
+
+// newWriter opens a broker that replaces an object only when it is closed after a complete write,
+// abortWrite discards what it was given.
+func newWriter(s storage.IStorage, helper storage.ParamsHelper, folder, key string) (storage.IWriteBroker, error) {
+	switch st := s.(type) {
+	case *storage.FileStorage:
+		return NewAtomicFileBroker(folder, key)
+	case *storage.OssStorage:
+		if helper == nil {
+			return nil, errors.New("oss storage must need a paramsHelper to specify buckert name")
+		}
+		return NewOssMultipartBroker(st.Client, helper().(string), folder, key)
+	}
+	// the s3 and memory brokers buffer and store on close
+	return s.Writer(key, helper)
+}
+
+// readObject reads a whole object. The local and oss readers of file_helper are avoided, the first
+// creates missing files and the second fails to open its stream.
+func readObject(s storage.IStorage, helper storage.ParamsHelper, folder, key string) ([]byte, error) {
+	switch st := s.(type) {
+	case *storage.FileStorage:
+		return os.ReadFile(filepath.Join(folder, key))
+	case *storage.OssStorage:
+		if helper == nil {
+			return nil, errors.New("oss storage must need a paramsHelper to specify buckert name")
+		}
+		bucket, err := st.Bucket(helper().(string))
+		if err != nil {
+			return nil, err
+		}
+		body, err := bucket.GetObject(path.Join(folder, key))
+		if err != nil {
+			return nil, err
+		}
+		defer body.Close()
+		return io.ReadAll(body)
+	}
+	broker, err := s.Reader(key, helper)
+	if err != nil {
+		return nil, err
+	}
+	defer broker.Close()
+	return io.ReadAll(broker)
+}
+
+// abortWrite discards the writes of a broker after a failure, brokers that can not abort are closed.
+func abortWrite(broker storage.IWriteBroker) {
+	if a, ok := broker.(interface{ Abort() error }); ok {
+		a.Abort()
+		return
+	}
+	broker.Close()
+}
+
+// closeWrite closes a broker after a complete write, and discards the writes if that fails.
+func closeWrite(broker storage.IWriteBroker) error {
+	if err := broker.Close(); err != nil {
+		abortWrite(broker)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package edge_tts_go
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestAtomicFileBroker(t *testing.T) {
+	folder := filepath.Join(t.TempDir(), "audio")
+	path := filepath.Join(folder, "a.mp3")
+
+	broker, err := NewAtomicFileBroker(folder, "a.mp3")
+	if err != nil {
+		t.Fatalf("NewAtomicFileBroker fail, err: %v", err)
+	}
+	broker.Write([]byte("partial"))
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("object should not exist before Close")
+	}
+	if err := broker.Abort(); err != nil {
+		t.Fatalf("Abort fail, err: %v", err)
+	}
+
+	broker, _ = NewAtomicFileBroker(folder, "a.mp3")
+	broker.Write([]byte("whole"))
+	if err := broker.Close(); err != nil {
+		t.Fatalf("Close fail, err: %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "whole" {
+		t.Errorf("object = %q", data)
+	}
+	if info, err := os.Stat(path); runtime.GOOS != "windows" && (err != nil || info.Mode().Perm() != objectMode) {
+		t.Errorf("object mode = %v, err: %v", info.Mode(), err)
+	}
+
+	// a failed refresh keeps the old object
+	broker, _ = NewAtomicFileBroker(folder, "a.mp3")
+	broker.Write([]byte("truncat"))
+	abortWrite(broker)
+	if data, _ := os.ReadFile(path); string(data) != "whole" {
+		t.Errorf("object = %q", data)
+	}
+
+	entries, _ := os.ReadDir(folder)
+	if len(entries) != 1 {
+		t.Errorf("temporary files should be removed, found %d files", len(entries))
+	}
+}
@@ -0,0 +1,207 @@
+package edge_tts_go
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"sync"
+
+	storage "github.com/pp-group/file-helper/storage"
+
+	"github.com/pp-group/edge-tts-go/biz/service/tts/edge"
+)
+
+// SynthesizeTo writes the audio of the request to w without any storage, such as an http response or a pipe.
+// The result has no URL and is never cached.
+func SynthesizeTo(ctx context.Context, w io.Writer, req Request) (*Result, error) {
+	c, err := edge.NewCommunicate(req.Text, req.Options...)
+	if err != nil {
+		return nil, err
+	}
+	// w may not start at offset 0, so the mp3 Xing frame is not written
+	synthesized, err := synthesize(ctx, c, struct{ io.Writer }{w})
+	if err != nil {
+		return nil, err
+	}
+	return &Result{
+		Key:        Key(c),
+		Size:       synthesized.size,
+		Duration:   synthesized.audio.Duration,
+		Format:     c.OutputFormat,
+		Boundaries: synthesized.boundaries,
+		Audio:      synthesized.audio,
+		Chunks:     synthesized.chunks,
+	}, nil
+}
+
+// WriteTo writes the audio of the speech to w, it makes Speech an io.WriterTo. Audio and Chunks
+// describe it afterwards.
+func (s *Speech) WriteTo(w io.Writer) (int64, error) {
+	synthesized, err := synthesize(context.Background(), s.Communicate, struct{ io.Writer }{w})
+	if err == nil {
+		s.Audio, s.Chunks = synthesized.audio, synthesized.chunks
+	}
+	return synthesized.size, err
+}
+
+// memoryURLPrefix prefixes the urls of objects held by a MemoryStorage.
+const memoryURLPrefix = "mem://"
+
+var _ storage.IStorage = new(MemoryStorage)
+
+// MemoryStorage holds objects in memory, it is meant for tests and short lived audio. Nothing bounds
+// it: every object and every write in progress is held whole until it is deleted.
+type MemoryStorage struct {
+	mu      sync.RWMutex
+	objects map[string][]byte
+}
+
+func NewMemoryStorage() *MemoryStorage {
+	return &MemoryStorage{objects: map[string][]byte{}}
+}
+
+func (s *MemoryStorage) Writer(objName string, helper storage.ParamsHelper) (storage.IWriteBroker, error) {
+	return &MemoryBroker{storage: s, name: objName}, nil
+}
+
+func (s *MemoryStorage) Reader(objName string, helper storage.ParamsHelper) (storage.IReadBroker, error) {
+	return &MemoryBroker{storage: s, name: objName}, nil
+}
+
+func (s *MemoryStorage) Manager(objName string, helper storage.ParamsHelper) (storage.IManageBroker, error) {
+	return &MemoryBroker{storage: s, name: objName}, nil
+}
+
+// Bytes returns a copy of an object.
+func (s *MemoryStorage) Bytes(objName string) ([]byte, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	data, ok := s.objects[objName]
+	if !ok {
+		return nil, false
+	}
+	return append([]byte{}, data...), true
+}
+
+func (s *MemoryStorage) stat(objName string) (int64, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	data, ok := s.objects[objName]
+	return int64(len(data)), ok, nil
+}
+
+var _ storage.IBroker = new(MemoryBroker)
+
+// MemoryBroker reads, writes and manages one object of a MemoryStorage, writes are stored on Close.
+type MemoryBroker struct {
+	storage *MemoryStorage
+	name    string
+	buf     *bytes.Buffer
+	reader  *bytes.Reader
+}
+
+func (broker *MemoryBroker) Write(p []byte) (int, error) {
+	if broker.buf == nil {
+		broker.buf = &bytes.Buffer{}
+	}
+	return broker.buf.Write(p)
+}
+
+// WriteAt rewrites what was written and not stored yet.
+func (broker *MemoryBroker) WriteAt(p []byte, off int64) (int, error) {
+	if broker.buf == nil || off < 0 || off+int64(len(p)) > int64(broker.buf.Len()) {
+		return 0, errors.New("memory broker can only rewrite what was written")
+	}
+	return copy(broker.buf.Bytes()[off:], p), nil
+}
+
+func (broker *MemoryBroker) Read(p []byte) (int, error) {
+	if broker.reader == nil {
+		data, ok := broker.storage.Bytes(broker.name)
+		if !ok {
+			return 0, os.ErrNotExist
+		}
+		broker.reader = bytes.NewReader(data)
+	}
+	return broker.reader.Read(p)
+}
+
+func (broker *MemoryBroker) Close() error {
+	broker.reader = nil
+	if broker.buf == nil {
+		return nil
+	}
+	broker.storage.mu.Lock()
+	defer broker.storage.mu.Unlock()
+	broker.storage.objects[broker.name] = broker.buf.Bytes()
+	broker.buf = nil
+	return nil
+}
+
+// Abort discards what was written.
+func (broker *MemoryBroker) Abort() error {
+	broker.buf = nil
+	return nil
+}
+
+func (broker *MemoryBroker) Exist() (bool, error) {
+	_, ok, err := broker.storage.stat(broker.name)
+	return ok, err
+}
+
+func (broker *MemoryBroker) URL() (string, error) {
+	if _, ok, _ := broker.storage.stat(broker.name); !ok {
+		return "", os.ErrNotExist
+	}
+	return memoryURLPrefix + broker.name, nil
+}
+
+func (broker *MemoryBroker) Delete(objName string) error {
+	broker.storage.mu.Lock()
+	defer broker.storage.mu.Unlock()
+	delete(broker.storage.objects, objName)
+	return nil
+}
+
+func NewMemorySpeechService(memoryStorage *MemoryStorage) *SpeechService {
+	return &SpeechService{
+		storage: memoryStorage,
+		stat:    memoryStorage.stat,
+	}
+}
+
+var _ ISpeech = new(MemorySpeech)
+
+type MemorySpeech struct {
+	*Speech
+	*MemoryStorage
+	service *SpeechService
+}
+
+// NewMemorySpeech returns a speech stored in memoryStorage, a new storage if it is nil.
+func NewMemorySpeech(c *edge.Communicate, memoryStorage *MemoryStorage) (*MemorySpeech, error) {
+	if memoryStorage == nil {
+		memoryStorage = NewMemoryStorage()
+	}
+
+	s, err := NewSpeech(c, memoryStorage, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return &MemorySpeech{
+		Speech:        s,
+		MemoryStorage: memoryStorage,
+		service:       NewMemorySpeechService(memoryStorage),
+	}, nil
+}
+
+func (speech *MemorySpeech) GenTTS() (string, func() error) {
+	return gentts(speech.Speech, speech.service)
+}
+
+func (speech *MemorySpeech) URL(filename string) (string, error) {
+	return objectURL(speech.IStorage, speech.service.helper, speech.Folder, filename, speech.URLOptions)
+}
@@ -0,0 +1,43 @@
+package edge_tts_go
+
+import (
+	"io"
+	"testing"
+
+	"github.com/pp-group/edge-tts-go/biz/service/tts/edge"
+)
+
+func TestMemorySpeech(t *testing.T) {
+	c, err := edge.NewCommunicate("cached")
+	if err != nil {
+		t.Fatalf("NewCommunicate fail, err: %v", err)
+	}
+	speech, err := NewMemorySpeech(c, nil)
+	if err != nil {
+		t.Fatalf("NewMemorySpeech fail, err: %v", err)
+	}
+	fileName, callback := speech.GenTTS()
+
+	w, _ := speech.Writer(fileName, nil)
+	w.Write([]byte("audio"))
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// the object exists, so no connection to the service is made
+	if err := callback(); err != nil {
+		t.Errorf("cached GenTTS fail, err: %v", err)
+	}
+	if u, err := speech.URL(fileName); err != nil || u != "mem://"+fileName {
+		t.Errorf("URL = %s, err: %v", u, err)
+	}
+
+	r, _ := speech.Reader(fileName, nil)
+	if data, err := io.ReadAll(r); err != nil || string(data) != "audio" {
+		t.Errorf("read %q, err: %v", data, err)
+	}
+	m, _ := speech.Manager(fileName, nil)
+	m.Delete(fileName)
+	if _, ok := speech.Bytes(fileName); ok {
+		t.Errorf("object should be deleted")
+	}
+}
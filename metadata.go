@@ -0,0 +1,140 @@
+package edge_tts_go
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"runtime/debug"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pp-group/edge-tts-go/biz/service/tts/edge"
+)
+
+// metadataSuffix is appended to the key of an audio object to name its sidecar.
+const metadataSuffix = ".json"
+
+const modulePath = "github.com/pp-group/edge-tts-go"
+
+// Metadata records what produced an audio object, it is stored as a json sidecar named <key>.json.
+// Times are in seconds, boundary offsets and durations in nanoseconds.
+type Metadata struct {
+	Key         string `json:"key"`
+	Voice       string `json:"voice"`
+	Rate        string `json:"rate"`
+	Volume      string `json:"volume"`
+	Pitch       string `json:"pitch"`
+	Style       string `json:"style,omitempty"`
+	StyleDegree string `json:"style_degree,omitempty"`
+	Role        string `json:"role,omitempty"`
+	Format      string `json:"format"`
+	// Text is empty when the service redacts it, TextHash is the sha256 of the text either way
+	Text       string  `json:"text,omitempty"`
+	TextHash   string  `json:"text_hash"`
+	Characters int     `json:"characters"`
+	Size       int64   `json:"size"`
+	Duration   float64 `json:"duration"`
+	// Audio describes the object and Chunks the audio of each message of it
+	Audio     AudioInfo       `json:"audio"`
+	Chunks    []AudioInfo     `json:"chunks"`
+	CreatedAt time.Time       `json:"created_at"`
+	Version   string          `json:"version"`
+	Words     []edge.Boundary `json:"words"`
+	Sentences []edge.Boundary `json:"sentences"`
+}
+
+// MetadataKey returns the key of the sidecar of an audio object.
+func MetadataKey(key string) string {
+	return key + metadataSuffix
+}
+
+func newMetadata(c *edge.Communicate, result *Result, redactText bool) *Metadata {
+	hash := sha256.Sum256([]byte(c.Text))
+	m := &Metadata{
+		Key:         result.Key,
+		Voice:       c.VoiceLangRegion,
+		Rate:        c.Rate,
+		Volume:      c.Volume,
+		Pitch:       c.Pitch,
+		Style:       c.Style,
+		StyleDegree: c.StyleDegree,
+		Role:        c.Role,
+		Format:      result.Format,
+		TextHash:    hex.EncodeToString(hash[:]),
+		Characters:  utf8.RuneCountInString(c.Text),
+		Size:        result.Size,
+		Duration:    result.Duration,
+		Audio:       result.Audio,
+		Chunks:      result.Chunks,
+		CreatedAt:   time.Now().UTC(),
+		Version:     version(),
+		Words:       []edge.Boundary{},
+		Sentences:   []edge.Boundary{},
+	}
+	if !redactText {
+		m.Text = c.Text
+	}
+	for _, b := range result.Boundaries {
+		if b.Type == edge.BoundarySentence {
+			m.Sentences = append(m.Sentences, b)
+		} else {
+			m.Words = append(m.Words, b)
+		}
+	}
+	return m
+}
+
+// version returns the version of this module in the binary, or devel when it is built from source.
+func version() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "devel"
+	}
+	if info.Main.Path == modulePath && info.Main.Version != "" {
+		return info.Main.Version
+	}
+	for _, dep := range info.Deps {
+		if dep.Path == modulePath {
+			return dep.Version
+		}
+	}
+	return "devel"
+}
+
+func (s *SpeechService) writeMetadata(m *Metadata) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeObject(s.storage, s.helper, s.folder, MetadataKey(m.Key), data)
+}
+
+// Metadata reads the sidecar of an audio object by its key, that is the file name returned by GenTTS.
+func (s *SpeechService) Metadata(key string) (*Metadata, error) {
+	data, err := readObject(s.storage, s.helper, s.folder, MetadataKey(key))
+	if err != nil {
+		return nil, fmt.Errorf("read metadata of %s err. %s", key, err.Error())
+	}
+	m := &Metadata{}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("decode metadata of %s err. %s", key, err.Error())
+	}
+	return m, nil
+}
+
+func (speech *LocalSpeech) Metadata(fileName string) (*Metadata, error) {
+	return speech.service.Metadata(fileName)
+}
+
+func (speech *OssSpeech) Metadata(fileName string) (*Metadata, error) {
+	return speech.service.Metadata(fileName)
+}
+
+func (speech *S3Speech) Metadata(fileName string) (*Metadata, error) {
+	return speech.service.Metadata(fileName)
+}
+
+func (speech *MemorySpeech) Metadata(fileName string) (*Metadata, error) {
+	return speech.service.Metadata(fileName)
+}
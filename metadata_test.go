@@ -0,0 +1,50 @@
+package edge_tts_go
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pp-group/edge-tts-go/biz/service/tts/edge"
+)
+
+func TestMetadata(t *testing.T) {
+	c, err := edge.NewCommunicate("你好，世界", edge.WithRate("+10%"))
+	if err != nil {
+		t.Fatalf("NewCommunicate fail, err: %v", err)
+	}
+	result := &Result{
+		Key:    Key(c),
+		Size:   12000,
+		Format: c.OutputFormat,
+		Boundaries: []edge.Boundary{
+			{Type: edge.BoundarySentence, Offset: 0, Duration: time.Second, Text: "你好，世界"},
+			{Type: edge.BoundaryWord, Offset: 0, Duration: 400 * time.Millisecond, Text: "你好"},
+		},
+	}
+	service, err := NewLocalSpeechService(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocalSpeechService fail, err: %v", err)
+	}
+	for _, redact := range []bool{false, true} {
+		if err := service.writeMetadata(newMetadata(c, result, redact)); err != nil {
+			t.Fatalf("writeMetadata fail, err: %v", err)
+		}
+		m, err := service.Metadata(result.Key)
+		if err != nil {
+			t.Fatalf("Metadata fail, err: %v", err)
+		}
+		if m.Voice != "zh-CN-XiaoxiaoNeural" || m.Rate != "+10%" || m.Characters != 5 || m.Size != 12000 || m.Version == "" {
+			t.Errorf("unexpected metadata: %+v", m)
+		}
+		if (m.Text == "") != redact || len(m.TextHash) != 64 {
+			t.Errorf("text %q, hash %q, redact %v", m.Text, m.TextHash, redact)
+		}
+		if len(m.Words) != 1 || len(m.Sentences) != 1 || m.Words[0].Duration != 400*time.Millisecond {
+			t.Errorf("unexpected boundaries: %+v %+v", m.Words, m.Sentences)
+		}
+	}
+
+	if _, err := service.Metadata("missing.mp3"); err == nil {
+		t.Errorf("missing metadata should fail")
+	}
+}
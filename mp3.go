@@ -0,0 +1,370 @@
+package edge_tts_go
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+)
+
+var (
+	mp3Bitrates1    = [15]int{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320}
+	mp3Bitrates2    = [15]int{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160}
+	mp3SampleRates1 = [3]int{44100, 48000, 32000}
+	mp3SampleRates2 = [3]int{22050, 24000, 16000}
+	mp3SampleRates3 = [3]int{11025, 12000, 8000}
+)
+
+const (
+	// xingFlags tells that the Xing frame has the frame count, the byte count and the TOC
+	xingFlags = 0x07
+	// xingSize is the size of the Xing data: the tag, the flags, the frame and byte counts and the TOC
+	xingSize = 4 + 4 + 4 + 4 + 100
+	// tocMarks is how many frame offsets mp3Writer keeps to build the TOC, at least the 100 entries of the TOC
+	tocMarks = 200
+)
+
+func isMP3(format string) bool {
+	return strings.HasSuffix(format, "-mp3")
+}
+
+// mp3Header is the header of an MPEG audio layer III frame.
+type mp3Header struct {
+	// mpeg1 is false for MPEG 2 and 2.5, their frames have half the samples
+	mpeg1      bool
+	bitrate    int
+	sampleRate int
+	channels   int
+	crc        bool
+	size       int
+}
+
+// parseMP3Header parses the 4 byte header of a layer III frame, free format frames are not supported.
+func parseMP3Header(b []byte) (mp3Header, bool) {
+	if len(b) < 4 || b[0] != 0xFF || b[1]&0xE0 != 0xE0 {
+		return mp3Header{}, false
+	}
+	version, layer := (b[1]>>3)&0x03, (b[1]>>1)&0x03
+	bitrateIndex, sampleRateIndex := b[2]>>4, (b[2]>>2)&0x03
+	if version == 1 || layer != 1 || bitrateIndex == 0 || bitrateIndex == 15 || sampleRateIndex == 3 {
+		return mp3Header{}, false
+	}
+
+	h := mp3Header{mpeg1: version == 3, crc: b[1]&0x01 == 0, channels: 2}
+	switch version {
+	case 3:
+		h.bitrate, h.sampleRate = mp3Bitrates1[bitrateIndex], mp3SampleRates1[sampleRateIndex]
+	case 2:
+		h.bitrate, h.sampleRate = mp3Bitrates2[bitrateIndex], mp3SampleRates2[sampleRateIndex]
+	default:
+		h.bitrate, h.sampleRate = mp3Bitrates2[bitrateIndex], mp3SampleRates3[sampleRateIndex]
+	}
+	if b[3]>>6 == 3 {
+		h.channels = 1
+	}
+	h.size = h.sizeAt(h.bitrate) + int((b[2]>>1)&0x01)
+	return h, true
+}
+
+// sizeAt is the size of a frame without padding at a bitrate in kbps.
+func (h mp3Header) sizeAt(bitrate int) int {
+	if h.mpeg1 {
+		return 144 * bitrate * 1000 / h.sampleRate
+	}
+	return 72 * bitrate * 1000 / h.sampleRate
+}
+
+func (h mp3Header) samples() int {
+	if h.mpeg1 {
+		return 1152
+	}
+	return 576
+}
+
+func (h mp3Header) duration() time.Duration {
+	return time.Duration(h.samples()) * time.Second / time.Duration(h.sampleRate)
+}
+
+// xingOffset is where the Xing data of a frame starts, after the header, the crc and the side information.
+func (h mp3Header) xingOffset() int {
+	offset := 4
+	if h.crc {
+		offset += 2
+	}
+	switch {
+	case h.mpeg1 && h.channels == 2:
+		offset += 32
+	case h.mpeg1, h.channels == 2:
+		offset += 17
+	default:
+		offset += 9
+	}
+	return offset
+}
+
+// isInfoFrame reports the Xing, Info and VBRI frames that describe a stream instead of holding audio,
+// the LAME tag is part of the Xing and Info frames.
+func isInfoFrame(frame []byte, h mp3Header) bool {
+	if offset := h.xingOffset(); len(frame) >= offset+4 {
+		if tag := string(frame[offset : offset+4]); tag == "Xing" || tag == "Info" {
+			return true
+		}
+	}
+	return len(frame) >= 36+4 && string(frame[36:40]) == "VBRI"
+}
+
+// mp3Writer checks the frames of mp3 streams written one after another and writes only their audio frames,
+// the ID3 tags and the Xing, Info and VBRI frames of every stream are dropped. When w is an io.WriterAt, the
+// first frame written is a Xing frame, finish rewrites it with the frame count, the byte count and the TOC
+// of the whole audio so players show its duration and seek in it. The encoder delay of each stream stays,
+// removing it needs decoding.
+type mp3Writer struct {
+	w  io.Writer
+	at io.WriterAt
+
+	// in counts the bytes given, pending holds the start of a frame that is not complete yet
+	in      int64
+	pending []byte
+	// skip is what is left of an ID3 tag
+	skip int64
+
+	first       []byte
+	firstHeader mp3Header
+	vbr         bool
+	xing        int
+	frames      int
+	audio       int64
+	// marks are the offsets in the audio of every stride-th frame
+	marks  []int64
+	stride int
+	// stream is the audio of the stream being written, streams the audio of the streams ended
+	stream  mp3Stream
+	streams []mp3Stream
+}
+
+// mp3Stream counts the audio frames kept of one stream, header is the one of its first frame.
+type mp3Stream struct {
+	header   mp3Header
+	frames   int
+	size     int64
+	duration time.Duration
+}
+
+func (s mp3Stream) info() AudioInfo {
+	info := AudioInfo{
+		Duration:   s.duration.Seconds(),
+		SampleRate: s.header.sampleRate,
+		Channels:   s.header.channels,
+		Size:       s.size,
+	}
+	if s.duration > 0 {
+		info.Bitrate = int(math.Round(float64(s.size*8) / s.duration.Seconds()))
+	}
+	return info
+}
+
+func newMP3Writer(w io.Writer) *mp3Writer {
+	m := &mp3Writer{w: w, stride: 1}
+	m.at, _ = w.(io.WriterAt)
+	return m
+}
+
+// size is the number of bytes written to w.
+func (m *mp3Writer) size() int64 {
+	return int64(m.xing) + m.audio
+}
+
+func (m *mp3Writer) Write(p []byte) (int, error) {
+	m.pending = append(m.pending, p...)
+	for len(m.pending) > 0 {
+		if m.skip > 0 {
+			n := int64(len(m.pending))
+			if n > m.skip {
+				n = m.skip
+			}
+			m.consume(int(n))
+			m.skip -= n
+			continue
+		}
+		if len(m.pending) < 10 {
+			break
+		}
+		switch string(m.pending[:3]) {
+		case "ID3":
+			size := int64(m.pending[6]&0x7F)<<21 | int64(m.pending[7]&0x7F)<<14 | int64(m.pending[8]&0x7F)<<7 | int64(m.pending[9]&0x7F)
+			m.skip = 10 + size
+			if m.pending[5]&0x10 != 0 {
+				// footer
+				m.skip += 10
+			}
+			continue
+		case "TAG":
+			// ID3v1 at the end of a stream
+			m.skip = 128
+			continue
+		}
+
+		h, ok := parseMP3Header(m.pending)
+		if !ok {
+			return 0, fmt.Errorf("mp3 frame sync lost at byte %d", m.in)
+		}
+		if len(m.pending) < h.size {
+			break
+		}
+		frame := m.pending[:h.size]
+		if !isInfoFrame(frame, h) {
+			if err := m.frame(frame, h); err != nil {
+				return 0, err
+			}
+		}
+		m.consume(h.size)
+	}
+	// the buffer is reused once it is drained
+	if len(m.pending) == 0 {
+		m.pending = m.pending[:0]
+	}
+	return len(p), nil
+}
+
+func (m *mp3Writer) consume(n int) {
+	m.pending = m.pending[n:]
+	m.in += int64(n)
+}
+
+func (m *mp3Writer) frame(frame []byte, h mp3Header) error {
+	if m.first == nil {
+		m.first = append([]byte{}, frame[:4]...)
+		m.firstHeader = h
+		if m.at != nil {
+			xing := m.xingFrame()
+			if _, err := m.w.Write(xing); err != nil {
+				return err
+			}
+			m.xing = len(xing)
+		}
+	} else if h.sampleRate != m.firstHeader.sampleRate || h.channels != m.firstHeader.channels {
+		return fmt.Errorf("mp3 stream of %dHz %d channels can not follow %dHz %d channels",
+			h.sampleRate, h.channels, m.firstHeader.sampleRate, m.firstHeader.channels)
+	}
+	if h.bitrate != m.firstHeader.bitrate {
+		m.vbr = true
+	}
+
+	if m.frames%m.stride == 0 {
+		m.marks = append(m.marks, m.audio)
+		if len(m.marks) == 2*tocMarks {
+			for i := 0; i < tocMarks; i++ {
+				m.marks[i] = m.marks[2*i]
+			}
+			m.marks = m.marks[:tocMarks]
+			m.stride *= 2
+		}
+	}
+	if _, err := m.w.Write(frame); err != nil {
+		return err
+	}
+	m.frames++
+	m.audio += int64(len(frame))
+	if m.stream.frames == 0 {
+		m.stream.header = h
+	}
+	m.stream.frames++
+	m.stream.size += int64(len(frame))
+	m.stream.duration += h.duration()
+	return nil
+}
+
+// endStream drops the truncated frame a stream may end with, the next write starts a new stream.
+func (m *mp3Writer) endStream() error {
+	m.in += int64(len(m.pending))
+	m.pending = m.pending[:0]
+	m.skip = 0
+	m.streams = append(m.streams, m.stream)
+	m.stream = mp3Stream{}
+	return nil
+}
+
+// finish rewrites the Xing frame with the counts and the TOC of the audio written.
+func (m *mp3Writer) finish() error {
+	if m.xing == 0 {
+		return nil
+	}
+	_, err := m.at.WriteAt(m.xingFrame(), 0)
+	return err
+}
+
+// xingFrame is a silent frame with the header of the first frame, at the lowest bitrate that holds the Xing data.
+func (m *mp3Writer) xingFrame() []byte {
+	h := m.firstHeader
+	h.crc = false
+	bitrates := mp3Bitrates2
+	if h.mpeg1 {
+		bitrates = mp3Bitrates1
+	}
+	index := 1
+	for index < len(bitrates)-1 && h.sizeAt(bitrates[index]) < h.xingOffset()+xingSize {
+		index++
+	}
+
+	frame := make([]byte, h.sizeAt(bitrates[index]))
+	copy(frame, m.first)
+	// no crc, no padding
+	frame[1] |= 0x01
+	frame[2] = byte(index)<<4 | m.first[2]&0x0D
+
+	tag := "Info"
+	if m.vbr {
+		tag = "Xing"
+	}
+	data := frame[h.xingOffset():]
+	copy(data, tag)
+	total := int64(len(frame)) + m.audio
+	binary.BigEndian.PutUint32(data[4:], xingFlags)
+	binary.BigEndian.PutUint32(data[8:], uint32(m.frames))
+	binary.BigEndian.PutUint32(data[12:], uint32(total))
+	// as LAME, the TOC has the offset in the file of every percent of the duration in 1/256 of the file,
+	// the Xing frame included as in the byte count
+	if m.frames > 0 {
+		for i := 0; i < 100; i++ {
+			data[16+i] = byte((int64(len(frame)) + m.marks[i*m.frames/100/m.stride]) * 256 / total)
+		}
+	}
+	return frame
+}
+
+// mp3Buffer is an in-memory io.Writer and io.WriterAt.
+type mp3Buffer struct {
+	data []byte
+}
+
+func (b *mp3Buffer) Write(p []byte) (int, error) {
+	b.data = append(b.data, p...)
+	return len(p), nil
+}
+
+func (b *mp3Buffer) WriteAt(p []byte, off int64) (int, error) {
+	if off < 0 || off+int64(len(p)) > int64(len(b.data)) {
+		return 0, errors.New("write past the end of the buffer")
+	}
+	return copy(b.data[off:], p), nil
+}
+
+// ConcatMP3 joins mp3 streams frame by frame into one with a single Xing frame, see mp3Writer.
+// The streams must have the same sample rate and channels.
+func ConcatMP3(streams ...[]byte) ([]byte, error) {
+	buf := &mp3Buffer{}
+	m := newMP3Writer(buf)
+	for i, stream := range streams {
+		if _, err := m.Write(stream); err != nil {
+			return nil, fmt.Errorf("mp3 stream %d: %s", i+1, err.Error())
+		}
+		m.endStream()
+	}
+	if err := m.finish(); err != nil {
+		return nil, err
+	}
+	return buf.data, nil
+}
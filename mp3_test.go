@@ -0,0 +1,94 @@
+package edge_tts_go
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// testFrame is a 144 byte MPEG 2 layer III frame at 24kHz mono 48kbps, its audio is fill.
+func testFrame(fill byte) []byte {
+	frame := bytes.Repeat([]byte{fill}, 144)
+	copy(frame, []byte{0xFF, 0xF3, 0x64, 0xC0})
+	return frame
+}
+
+func testInfoFrame(tag string) []byte {
+	frame := testFrame(0)
+	copy(frame[4+9:], tag)
+	return frame
+}
+
+func TestConcatMP3(t *testing.T) {
+	id3 := append([]byte("ID3\x03\x00\x00\x00\x00\x00\x05"), "title"...)
+	first := bytes.Join([][]byte{id3, testInfoFrame("Info"), testFrame(1), testFrame(2), testFrame(3)}, nil)
+	// the second stream ends with a truncated frame
+	second := bytes.Join([][]byte{testInfoFrame("Xing"), testFrame(4), testFrame(5), testFrame(6)[:50]}, nil)
+
+	merged, err := ConcatMP3(first, second)
+	if err != nil {
+		t.Fatalf("ConcatMP3 fail, err: %v", err)
+	}
+	if len(merged) != 6*144 {
+		t.Fatalf("merged %d bytes", len(merged))
+	}
+	xing := merged[:144]
+	if h, ok := parseMP3Header(xing); !ok || h.sampleRate != 24000 || h.channels != 1 || !isInfoFrame(xing, h) {
+		t.Fatalf("first frame is not an Info frame: % x", xing[:20])
+	}
+	data := xing[13:]
+	if string(data[:4]) != "Info" || binary.BigEndian.Uint32(data[4:]) != xingFlags ||
+		binary.BigEndian.Uint32(data[8:]) != 5 || binary.BigEndian.Uint32(data[12:]) != uint32(len(merged)) {
+		t.Errorf("unexpected Xing data: % x", data[:16])
+	}
+	// every 20% of the duration is a frame, 1/6 of the file with the Xing frame
+	if toc := data[16:116]; toc[0] != 42 || toc[20] != 85 || toc[40] != 128 || toc[99] != 213 {
+		t.Errorf("unexpected TOC: %v", toc)
+	}
+	for i := 1; i < 6; i++ {
+		if frame := merged[i*144 : (i+1)*144]; !bytes.Equal(frame, testFrame(byte(i))) {
+			t.Errorf("frame %d = % x", i, frame[:8])
+		}
+	}
+
+	if _, err := ConcatMP3(first, []byte("not an mp3 stream")); err == nil {
+		t.Errorf("lost sync should fail")
+	}
+	stereo := testFrame(7)
+	stereo[3] = 0x00
+	if _, err := ConcatMP3(first, stereo); err == nil {
+		t.Errorf("streams of different channels should fail")
+	}
+}
+
+func TestMP3WriterTOC(t *testing.T) {
+	m := newMP3Writer(&mp3Buffer{})
+	for i := 0; i < 1000; i++ {
+		frame := testFrame(0)
+		if i%2 == 1 {
+			// 56kbps
+			frame = make([]byte, 168)
+			copy(frame, []byte{0xFF, 0xF3, 0x74, 0xC0})
+		}
+		if _, err := m.Write(frame); err != nil {
+			t.Fatalf("Write fail, err: %v", err)
+		}
+	}
+	m.endStream()
+	if m.frames != 1000 || !m.vbr || len(m.streams) != 1 || m.streams[0].duration.Milliseconds() != 24000 {
+		t.Fatalf("frames = %d, vbr = %v, streams = %+v", m.frames, m.vbr, m.streams)
+	}
+	// 500 frames of 144 and 168 bytes in 24s
+	if info := m.streams[0].info(); info.Bitrate != 52000 || info.SampleRate != 24000 || info.Channels != 1 || info.Size != 156000 {
+		t.Errorf("unexpected info: %+v", info)
+	}
+	toc := m.xingFrame()[13+16 : 13+116]
+	for i := 1; i < 100; i++ {
+		if toc[i] < toc[i-1] {
+			t.Fatalf("TOC is not ordered: %v", toc)
+		}
+	}
+	if toc[50] != 128 {
+		t.Errorf("TOC[50] = %d", toc[50])
+	}
+}
@@ -0,0 +1,150 @@
+package edge_tts_go
+
+import (
+	"bytes"
+	"errors"
+	"path"
+	"sort"
+
+	"github.com/aliyun/aliyun-oss-go-sdk/oss"
+	storage "github.com/pp-group/file-helper/storage"
+)
+
+const (
+	// ossPartSize is the size of the parts of a multipart upload, oss needs at least 100KB but for the last part
+	ossPartSize = 1 << 20
+	// ossURLExpires is the lifetime in seconds of signed urls, as the file_helper broker
+	ossURLExpires = 300
+	// ossUploadStatus is the meta the file_helper broker checks before reading an object
+	ossUploadStatus = "File-Upload-Status"
+)
+
+var _ storage.IWriteBroker = new(OssMultipartBroker)
+
+// OssMultipartBroker uploads an object in parts of ossPartSize while it is written. The first part is
+// uploaded last so WriteAt can rewrite it, so at most two parts are held in memory. The object only
+// appears, replacing any older one, when Close completes the upload.
+type OssMultipartBroker struct {
+	bucket *oss.Bucket
+	key    string
+	imur   *oss.InitiateMultipartUploadResult
+	parts  []oss.UploadPart
+	first  []byte
+	buf    bytes.Buffer
+	err    error
+}
+
+func NewOssMultipartBroker(client *oss.Client, bucketName, folder, objName string) (*OssMultipartBroker, error) {
+	bucket, err := client.Bucket(bucketName)
+	if err != nil {
+		return nil, err
+	}
+	return &OssMultipartBroker{
+		bucket: bucket,
+		key:    path.Join(folder, objName),
+	}, nil
+}
+
+func (broker *OssMultipartBroker) Write(p []byte) (int, error) {
+	if broker.err != nil {
+		return 0, broker.err
+	}
+	broker.buf.Write(p)
+	for broker.buf.Len() >= ossPartSize {
+		if broker.first == nil {
+			broker.first = append([]byte{}, broker.buf.Next(ossPartSize)...)
+			continue
+		}
+		if err := broker.uploadPart(broker.buf.Next(ossPartSize), len(broker.parts)+2); err != nil {
+			broker.err = err
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// WriteAt rewrites what was written to the first part, such as the mp3 Xing frame.
+func (broker *OssMultipartBroker) WriteAt(p []byte, off int64) (int, error) {
+	if broker.err != nil {
+		return 0, broker.err
+	}
+	held := broker.first
+	if held == nil {
+		held = broker.buf.Bytes()
+	}
+	if off < 0 || off+int64(len(p)) > int64(len(held)) {
+		return 0, errors.New("oss broker can only rewrite the first part")
+	}
+	return copy(held[off:], p), nil
+}
+
+func (broker *OssMultipartBroker) uploadPart(data []byte, number int) error {
+	if broker.imur == nil {
+		// the object only exists once complete, so it is finished for the readers of file_helper
+		imur, err := broker.bucket.InitiateMultipartUpload(broker.key, oss.Meta(ossUploadStatus, "Finished"))
+		if err != nil {
+			return err
+		}
+		broker.imur = &imur
+	}
+	part, err := broker.bucket.UploadPart(*broker.imur, bytes.NewReader(data), int64(len(data)), number)
+	if err != nil {
+		return err
+	}
+	broker.parts = append(broker.parts, part)
+	return nil
+}
+
+// Close uploads the last part and the first one and completes the upload, after a failed write it aborts the upload.
+func (broker *OssMultipartBroker) Close() error {
+	if broker.err != nil {
+		err := broker.err
+		broker.Abort()
+		return err
+	}
+	var err error
+	if broker.first == nil {
+		err = broker.uploadPart(broker.buf.Bytes(), 1)
+	} else {
+		if broker.buf.Len() > 0 {
+			err = broker.uploadPart(broker.buf.Bytes(), len(broker.parts)+2)
+		}
+		if err == nil {
+			err = broker.uploadPart(broker.first, 1)
+		}
+	}
+	if err != nil {
+		broker.Abort()
+		return err
+	}
+	sort.Slice(broker.parts, func(i, j int) bool {
+		return broker.parts[i].PartNumber < broker.parts[j].PartNumber
+	})
+	if _, err := broker.bucket.CompleteMultipartUpload(*broker.imur, broker.parts); err != nil {
+		broker.Abort()
+		return err
+	}
+	broker.first = nil
+	broker.buf.Reset()
+	broker.err = errors.New("oss broker is closed")
+	return nil
+}
+
+// Abort aborts the upload, the parts uploaded so far are deleted.
+func (broker *OssMultipartBroker) Abort() error {
+	broker.err = errors.New("oss broker is aborted")
+	if broker.imur == nil {
+		return nil
+	}
+	imur := broker.imur
+	broker.imur = nil
+	return broker.bucket.AbortMultipartUpload(*imur)
+}
+
+func (broker *OssMultipartBroker) Exist() (bool, error) {
+	return broker.bucket.IsObjectExist(broker.key)
+}
+
+func (broker *OssMultipartBroker) URL() (string, error) {
+	return broker.bucket.SignURL(broker.key, oss.HTTPGet, ossURLExpires)
+}
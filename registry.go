@@ -0,0 +1,209 @@
+package edge_tts_go
+
+import (
+	"errors"
+	"fmt"
+	neturl "net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pp-group/edge-tts-go/biz/service/tts/edge"
+)
+
+// SpeechBuilder builds the speech of a Communicate from a storage url of its scheme.
+type SpeechBuilder func(c *edge.Communicate, u *neturl.URL) (ISpeech, error)
+
+var (
+	schemesMu sync.RWMutex
+	schemes   = map[string]SpeechBuilder{}
+
+	memoryStoragesMu sync.Mutex
+	memoryStorages   = map[string]*MemoryStorage{}
+)
+
+func init() {
+	RegisterScheme("file", buildLocalSpeech)
+	RegisterScheme("oss", buildOssSpeech)
+	RegisterScheme("s3", buildS3Speech)
+	RegisterScheme("mem", buildMemorySpeech)
+}
+
+// RegisterScheme makes NewSpeechFromURL build the speeches of a url scheme with builder,
+// registering a scheme again replaces its builder.
+func RegisterScheme(scheme string, builder SpeechBuilder) {
+	if scheme == "" || builder == nil {
+		panic("edge_tts_go: RegisterScheme needs a scheme and a builder")
+	}
+	schemesMu.Lock()
+	defer schemesMu.Unlock()
+	schemes[strings.ToLower(scheme)] = builder
+}
+
+// NewSpeechFromURL builds the speech of a Communicate from a storage url:
+//
+//	file:///var/tts?base_url=/audio
+//	oss://bucket/prefix?endpoint=oss-cn-hangzhou.aliyuncs.com&access_key_id=...&access_key_secret=...
+//	s3://bucket/prefix?endpoint=http://localhost:9000&region=us-east-1&path_style=true
+//	mem://name
+//
+// Every builtin scheme reads expires and cdn from the query as URLOptions. Credentials missing from the
+// query are read from OSS_ACCESS_KEY_ID and OSS_ACCESS_KEY_SECRET, or AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY
+// and AWS_SESSION_TOKEN.
+func NewSpeechFromURL(c *edge.Communicate, rawURL string) (ISpeech, error) {
+	u, err := neturl.Parse(rawURL)
+	if err != nil {
+		// the error quotes the url, credentials of its query must not reach the logs
+		if urlErr, ok := err.(*neturl.Error); ok {
+			err = urlErr.Err
+		}
+		return nil, fmt.Errorf("parse storage url err. %s", err.Error())
+	}
+	schemesMu.RLock()
+	builder, ok := schemes[strings.ToLower(u.Scheme)]
+	schemesMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown storage scheme %q", u.Scheme)
+	}
+	return builder(c, u)
+}
+
+// urlOptions reads the URLOptions of the query of a storage url.
+func urlOptions(u *neturl.URL) (URLOptions, error) {
+	q := u.Query()
+	opts := URLOptions{CDNHost: q.Get("cdn"), LocalBaseURL: q.Get("base_url")}
+	if v := q.Get("expires"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return opts, fmt.Errorf("invalid expires %s", v)
+		}
+		opts.Expires = d
+	}
+	return opts, opts.validate()
+}
+
+// bucketAndFolder returns the bucket in the host of a url and the folder in its path.
+func bucketAndFolder(u *neturl.URL) (string, string, error) {
+	if u.Host == "" {
+		return "", "", fmt.Errorf("%s url must name a bucket", u.Scheme)
+	}
+	return u.Host, strings.Trim(u.Path, "/"), nil
+}
+
+func queryOrEnv(q neturl.Values, key, env string) string {
+	if v := q.Get(key); v != "" {
+		return v
+	}
+	return os.Getenv(env)
+}
+
+func buildLocalSpeech(c *edge.Communicate, u *neturl.URL) (ISpeech, error) {
+	opts, err := urlOptions(u)
+	if err != nil {
+		return nil, err
+	}
+	folder := filepath.FromSlash(u.Path)
+	if u.Host != "" && u.Host != "localhost" {
+		// file://audio/tts is the relative folder audio/tts
+		folder = filepath.Join(u.Host, folder)
+	}
+	if folder == "" {
+		return nil, errors.New("file url must name a folder")
+	}
+	speech, err := NewLocalSpeech(c, folder)
+	if err != nil {
+		return nil, err
+	}
+	speech.URLOptions = opts
+	return speech, nil
+}
+
+func buildOssSpeech(c *edge.Communicate, u *neturl.URL) (ISpeech, error) {
+	opts, err := urlOptions(u)
+	if err != nil {
+		return nil, err
+	}
+	bucket, folder, err := bucketAndFolder(u)
+	if err != nil {
+		return nil, err
+	}
+	q := u.Query()
+	endpoint := q.Get("endpoint")
+	if endpoint == "" {
+		return nil, errors.New("oss url must have an endpoint")
+	}
+	speech, err := NewOssSpeech(c, endpoint, queryOrEnv(q, "access_key_id", "OSS_ACCESS_KEY_ID"),
+		queryOrEnv(q, "access_key_secret", "OSS_ACCESS_KEY_SECRET"), folder, bucket)
+	if err != nil {
+		return nil, err
+	}
+	speech.URLOptions = opts
+	return speech, nil
+}
+
+func buildS3Speech(c *edge.Communicate, u *neturl.URL) (ISpeech, error) {
+	opts, err := urlOptions(u)
+	if err != nil {
+		return nil, err
+	}
+	bucket, folder, err := bucketAndFolder(u)
+	if err != nil {
+		return nil, err
+	}
+	q := u.Query()
+	config := S3Config{
+		Endpoint:     q.Get("endpoint"),
+		Region:       queryOrEnv(q, "region", "AWS_REGION"),
+		Bucket:       bucket,
+		AccessKey:    queryOrEnv(q, "access_key", "AWS_ACCESS_KEY_ID"),
+		SecretKey:    queryOrEnv(q, "secret_key", "AWS_SECRET_ACCESS_KEY"),
+		SessionToken: queryOrEnv(q, "session_token", "AWS_SESSION_TOKEN"),
+		Expires:      opts.Expires,
+	}
+	if v := q.Get("path_style"); v != "" {
+		if config.PathStyle, err = strconv.ParseBool(v); err != nil {
+			return nil, fmt.Errorf("invalid path_style %s", v)
+		}
+	}
+	if config.Region == "" {
+		config.Region = "us-east-1"
+	}
+	if config.Endpoint == "" {
+		config.Endpoint = "https://s3." + config.Region + ".amazonaws.com"
+	}
+	speech, err := NewS3Speech(c, config, folder)
+	if err != nil {
+		return nil, err
+	}
+	speech.URLOptions = opts
+	return speech, nil
+}
+
+// buildMemorySpeech stores the speeches of mem://name in one MemoryStorage per name for the life of the process.
+func buildMemorySpeech(c *edge.Communicate, u *neturl.URL) (ISpeech, error) {
+	opts, err := urlOptions(u)
+	if err != nil {
+		return nil, err
+	}
+	speech, err := NewMemorySpeech(c, MemoryStorageByName(u.Host+u.Path))
+	if err != nil {
+		return nil, err
+	}
+	speech.URLOptions = opts
+	return speech, nil
+}
+
+// MemoryStorageByName returns the MemoryStorage that mem://name urls share.
+func MemoryStorageByName(name string) *MemoryStorage {
+	memoryStoragesMu.Lock()
+	defer memoryStoragesMu.Unlock()
+	s, ok := memoryStorages[name]
+	if !ok {
+		s = NewMemoryStorage()
+		memoryStorages[name] = s
+	}
+	return s
+}
@@ -0,0 +1,66 @@
+package edge_tts_go
+
+import (
+	"errors"
+	neturl "net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pp-group/edge-tts-go/biz/service/tts/edge"
+)
+
+func TestNewSpeechFromURL(t *testing.T) {
+	c, err := edge.NewCommunicate("registry")
+	if err != nil {
+		t.Fatalf("NewCommunicate fail, err: %v", err)
+	}
+
+	dir := t.TempDir()
+	speech, err := NewSpeechFromURL(c, "file://"+filepath.ToSlash(dir)+"?base_url=/audio")
+	if err != nil {
+		t.Fatalf("file url fail, err: %v", err)
+	}
+	local, ok := speech.(*LocalSpeech)
+	if !ok || local.Folder != dir || local.URLOptions.LocalBaseURL != "/audio" {
+		t.Errorf("file url built %#v", speech)
+	}
+
+	speech, err = NewSpeechFromURL(c, "s3://bucket/tts/audio?endpoint=http://localhost:9000&path_style=true&expires=10m&access_key=ak&secret_key=sk")
+	if err != nil {
+		t.Fatalf("s3 url fail, err: %v", err)
+	}
+	if s3, ok := speech.(*S3Speech); !ok || s3.Folder != "tts/audio" || !s3.IStorage.(*S3Storage).config.PathStyle {
+		t.Errorf("s3 url built %#v", speech)
+	}
+
+	a, _ := NewSpeechFromURL(c, "mem://shared")
+	b, _ := NewSpeechFromURL(c, "mem://shared")
+	if a.(*MemorySpeech).MemoryStorage != b.(*MemorySpeech).MemoryStorage {
+		t.Errorf("mem urls of one name should share a storage")
+	}
+
+	for _, rawURL := range []string{
+		"ftp://host/tts",
+		"oss:///prefix?endpoint=oss-cn-hangzhou.aliyuncs.com",
+		"oss://bucket/prefix",
+		"s3://bucket?path_style=maybe",
+		"mem://?expires=1ms",
+	} {
+		if _, err := NewSpeechFromURL(c, rawURL); err == nil {
+			t.Errorf("%s should fail", rawURL)
+		}
+	}
+
+	if _, err := NewSpeechFromURL(c, "s3://bucket/%zz?secret_key=topsecret"); err == nil || strings.Contains(err.Error(), "topsecret") {
+		t.Errorf("malformed url err: %v", err)
+	}
+
+	errCustom := errors.New("custom")
+	RegisterScheme("custom", func(c *edge.Communicate, u *neturl.URL) (ISpeech, error) {
+		return nil, errCustom
+	})
+	if _, err := NewSpeechFromURL(c, "CUSTOM://anything"); err != errCustom {
+		t.Errorf("custom scheme err: %v", err)
+	}
+}
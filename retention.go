@@ -0,0 +1,274 @@
+package edge_tts_go
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// staleTempAge is how old a temporary file must be before a sweep removes it, younger ones may still be written.
+const staleTempAge = time.Hour
+
+// RetentionPolicy limits a local folder of objects. An object is removed together with its sidecars,
+// the files named after it such as <key>.json and <key>.srt. Zero values do not limit.
+type RetentionPolicy struct {
+	// MaxBytes is the total size of the folder, the least recently used objects are removed above it
+	MaxBytes int64
+	// MaxAge removes objects written longer ago, they are synthesized again on their next request
+	MaxAge time.Duration
+}
+
+// RetentionStats describes a local folder and the cache lookups recorded by a Retention.
+type RetentionStats struct {
+	Files int
+	Bytes int64
+	// Hits and Misses count the lookups of the services that use the Retention since it was created
+	Hits    int64
+	Misses  int64
+	HitRate float64
+	// Evicted and EvictedBytes count the files removed by sweeps, sidecars and stale temporary files included
+	Evicted      int64
+	EvictedBytes int64
+	LastSweep    time.Time
+	// LastError is the error of the last sweep
+	LastError error
+}
+
+// Retention keeps a local folder within a RetentionPolicy. Set it on the services or speeches that write to
+// the folder, so cache hits refresh the access time of the objects and are counted, then call Sweep or Run.
+// Access times are set explicitly, so eviction works on file systems mounted with noatime.
+type Retention struct {
+	folder string
+	policy RetentionPolicy
+	now    func() time.Time
+
+	hits         atomic.Int64
+	misses       atomic.Int64
+	evicted      atomic.Int64
+	evictedBytes atomic.Int64
+
+	mu        sync.Mutex
+	lastSweep time.Time
+	lastErr   error
+}
+
+func NewRetention(folder string, policy RetentionPolicy) *Retention {
+	return &Retention{folder: folder, policy: policy, now: time.Now}
+}
+
+// hit counts a cache hit and marks the object as used.
+func (r *Retention) hit(key string) {
+	r.hits.Add(1)
+	path := filepath.Join(r.folder, key)
+	if info, err := os.Stat(path); err == nil {
+		os.Chtimes(path, r.now(), info.ModTime())
+	}
+}
+
+func (r *Retention) miss() {
+	r.misses.Add(1)
+}
+
+// Run sweeps the folder now and every interval until ctx is done, errors are kept in RetentionStats.LastError.
+func (r *Retention) Run(ctx context.Context, interval time.Duration) error {
+	if interval <= 0 {
+		return errors.New("retention interval must be positive")
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		r.Sweep()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// retainedObject is a stored object with its sidecars.
+type retainedObject struct {
+	paths      []string
+	bytes      int64
+	modified   time.Time
+	lastAccess time.Time
+}
+
+// Sweep removes the stale temporary files, the objects older than MaxAge and then the least recently
+// used objects until the folder fits in MaxBytes.
+func (r *Retention) Sweep() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := r.now()
+	err := r.sweep(now)
+	r.lastSweep, r.lastErr = now, err
+	return err
+}
+
+func (r *Retention) sweep(now time.Time) error {
+	objects, err := r.scan(now)
+	if err != nil {
+		return err
+	}
+
+	var total int64
+	var kept []*retainedObject
+	for _, o := range objects {
+		if r.policy.MaxAge > 0 && now.Sub(o.modified) > r.policy.MaxAge {
+			if err := r.remove(o.paths, o.bytes); err != nil {
+				return err
+			}
+			continue
+		}
+		total += o.bytes
+		kept = append(kept, o)
+	}
+	if r.policy.MaxBytes <= 0 || total <= r.policy.MaxBytes {
+		return nil
+	}
+
+	sort.Slice(kept, func(i, j int) bool {
+		return kept[i].lastAccess.Before(kept[j].lastAccess)
+	})
+	for _, o := range kept {
+		if total <= r.policy.MaxBytes {
+			break
+		}
+		if err := r.remove(o.paths, o.bytes); err != nil {
+			return err
+		}
+		total -= o.bytes
+	}
+	return nil
+}
+
+// scan groups the files of the folder by object and removes stale temporary files.
+func (r *Retention) scan(now time.Time) ([]*retainedObject, error) {
+	files := map[string]fs.FileInfo{}
+	err := filepath.WalkDir(r.folder, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if isTempFile(d.Name()) {
+			if now.Sub(info.ModTime()) > staleTempAge {
+				return r.remove([]string{path}, info.Size())
+			}
+			return nil
+		}
+		files[path] = info
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	objects := map[string]*retainedObject{}
+	for path, info := range files {
+		key := sidecarOf(path, files)
+		o, ok := objects[key]
+		if !ok {
+			o = &retainedObject{}
+			objects[key] = o
+		}
+		o.paths = append(o.paths, path)
+		o.bytes += info.Size()
+		if path == key {
+			o.modified = info.ModTime()
+		}
+		if access := accessTime(info); access.After(o.lastAccess) {
+			o.lastAccess = access
+		}
+	}
+	list := make([]*retainedObject, 0, len(objects))
+	for _, o := range objects {
+		list = append(list, o)
+	}
+	return list, nil
+}
+
+// sidecarOf returns the file a sidecar is named after, <key>.json belongs to <key>, or path itself.
+func sidecarOf(path string, files map[string]fs.FileInfo) string {
+	base := len(filepath.Dir(path)) + 1
+	for i := base; i < len(path); i++ {
+		if path[i] != '.' || i == base {
+			continue
+		}
+		if _, ok := files[path[:i]]; ok {
+			return path[:i]
+		}
+	}
+	return path
+}
+
+// isTempFile reports the temporary files of AtomicFileBroker.
+func isTempFile(name string) bool {
+	return strings.HasPrefix(name, ".") && strings.HasSuffix(name, tempSuffix)
+}
+
+func (r *Retention) remove(paths []string, bytes int64) error {
+	for _, path := range paths {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	r.evicted.Add(int64(len(paths)))
+	r.evictedBytes.Add(bytes)
+	return nil
+}
+
+// Stats scans the folder and returns it with the recorded lookups and sweeps.
+func (r *Retention) Stats() (RetentionStats, error) {
+	stats := RetentionStats{
+		Hits:         r.hits.Load(),
+		Misses:       r.misses.Load(),
+		Evicted:      r.evicted.Load(),
+		EvictedBytes: r.evictedBytes.Load(),
+	}
+	if lookups := stats.Hits + stats.Misses; lookups > 0 {
+		stats.HitRate = float64(stats.Hits) / float64(lookups)
+	}
+	r.mu.Lock()
+	stats.LastSweep, stats.LastError = r.lastSweep, r.lastErr
+	r.mu.Unlock()
+
+	err := filepath.WalkDir(r.folder, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() || isTempFile(d.Name()) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			// removed since it was listed
+			return nil
+		}
+		stats.Files++
+		stats.Bytes += info.Size()
+		return nil
+	})
+	return stats, err
+}
@@ -0,0 +1,87 @@
+package edge_tts_go
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pp-group/edge-tts-go/biz/service/tts/edge"
+)
+
+func TestRetention(t *testing.T) {
+	folder := t.TempDir()
+	service, err := NewLocalSpeechService(folder)
+	if err != nil {
+		t.Fatalf("NewLocalSpeechService fail, err: %v", err)
+	}
+	retention := NewRetention(folder, RetentionPolicy{MaxBytes: 150, MaxAge: 24 * time.Hour})
+	service.Retention = retention
+
+	req := Request{Text: "retained"}
+	c, err := edge.NewCommunicate(req.Text)
+	if err != nil {
+		t.Fatalf("NewCommunicate fail, err: %v", err)
+	}
+	now := time.Now()
+	files := []struct {
+		name   string
+		size   int
+		access time.Time
+		modify time.Time
+	}{
+		{Key(c), 100, now.Add(-time.Hour), now.Add(-time.Hour)},
+		{"lru.mp3", 100, now.Add(-30 * time.Minute), now.Add(-2 * time.Hour)},
+		{"lru.mp3.json", 10, now.Add(-2 * time.Hour), now.Add(-2 * time.Hour)},
+		{"old.mp3", 10, now, now.Add(-48 * time.Hour)},
+		{".old.mp3.1.tmp", 10, now.Add(-2 * time.Hour), now.Add(-2 * time.Hour)},
+		{".new.mp3.2.tmp", 10, now, now},
+	}
+	for _, f := range files {
+		path := filepath.Join(folder, f.name)
+		if err := os.WriteFile(path, make([]byte, f.size), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, f.access, f.modify); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the hit makes the object the most recently used one
+	if result, err := service.Synthesize(context.Background(), req); err != nil || !result.Cached {
+		t.Fatalf("Synthesize = %+v, err: %v", result, err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := service.Synthesize(ctx, Request{Text: "missed"}); err == nil {
+		t.Errorf("Synthesize with a canceled context should fail")
+	}
+
+	if err := retention.Sweep(); err != nil {
+		t.Fatalf("Sweep fail, err: %v", err)
+	}
+	for _, name := range []string{"lru.mp3", "lru.mp3.json", "old.mp3", ".old.mp3.1.tmp"} {
+		if _, err := os.Stat(filepath.Join(folder, name)); !os.IsNotExist(err) {
+			t.Errorf("%s should be removed, err: %v", name, err)
+		}
+	}
+	for _, name := range []string{Key(c), ".new.mp3.2.tmp"} {
+		if _, err := os.Stat(filepath.Join(folder, name)); err != nil {
+			t.Errorf("%s should be kept, err: %v", name, err)
+		}
+	}
+
+	stats, err := retention.Stats()
+	if err != nil {
+		t.Fatalf("Stats fail, err: %v", err)
+	}
+	if stats.Files != 1 || stats.Bytes != 100 || stats.Hits != 1 || stats.Misses != 1 || stats.HitRate != 0.5 ||
+		stats.Evicted != 4 || stats.EvictedBytes != 130 || stats.LastSweep.IsZero() || stats.LastError != nil {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+
+	if err := retention.Run(context.Background(), 0); err == nil {
+		t.Errorf("Run with no interval should fail")
+	}
+}
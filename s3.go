@@ -0,0 +1,620 @@
+package edge_tts_go
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	neturl "net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	storage "github.com/pp-group/file-helper/storage"
+
+	"github.com/pp-group/edge-tts-go/biz/service/tts/edge"
+)
+
+const (
+	s3Algorithm       = "AWS4-HMAC-SHA256"
+	s3UnsignedPayload = "UNSIGNED-PAYLOAD"
+	s3TimeFormat      = "20060102T150405Z"
+	s3DateFormat      = "20060102"
+	// s3DefaultExpires is the lifetime of presigned urls unless S3Config.Expires is set
+	s3DefaultExpires = 15 * time.Minute
+	// s3MaxExpires is the longest lifetime signature v4 allows
+	s3MaxExpires = 7 * 24 * time.Hour
+	// s3PartSize is the size of the parts of a multipart upload, s3 needs at least 5MB but for the last part
+	s3PartSize = 5 << 20
+)
+
+// S3Config configures an S3 compatible storage, such as AWS S3 or MinIO.
+type S3Config struct {
+	// Endpoint is the scheme and host of the service, such as https://s3.us-west-2.amazonaws.com or http://localhost:9000
+	Endpoint string
+	Region   string
+	Bucket   string
+
+	AccessKey    string
+	SecretKey    string
+	SessionToken string
+
+	// PathStyle addresses objects as endpoint/bucket/key instead of bucket.endpoint/key, MinIO usually needs it
+	PathStyle bool
+	// Expires is the lifetime of the presigned urls returned by URL
+	Expires time.Duration
+	// Client sends the requests, http.DefaultClient if nil
+	Client *http.Client
+}
+
+var _ storage.IStorage = new(S3Storage)
+
+// S3Storage stores objects in a bucket of an S3 compatible service, signing the requests with signature v4.
+type S3Storage struct {
+	config   S3Config
+	endpoint *neturl.URL
+	folder   string
+	// now is the clock of the signatures
+	now func() time.Time
+}
+
+func NewS3Storage(config S3Config, folder string) (*S3Storage, error) {
+	if config.Endpoint == "" {
+		return nil, errors.New("s3 storage must have an endpoint")
+	}
+	if config.Bucket == "" {
+		return nil, errors.New("s3 storage must have a bucket")
+	}
+	if config.AccessKey == "" || config.SecretKey == "" {
+		return nil, errors.New("s3 storage must have credentials")
+	}
+	if config.Region == "" {
+		config.Region = "us-east-1"
+	}
+	if config.Expires == 0 {
+		config.Expires = s3DefaultExpires
+	}
+	if config.Expires < time.Second || config.Expires > s3MaxExpires {
+		return nil, errors.New("s3 url expires must be between 1s and 7 days")
+	}
+	if config.Client == nil {
+		config.Client = http.DefaultClient
+	}
+	endpoint, err := neturl.Parse(config.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("parse s3 endpoint err. %s", err.Error())
+	}
+	if endpoint.Scheme == "" || endpoint.Host == "" {
+		return nil, errors.New("s3 endpoint must have a scheme and a host")
+	}
+	return &S3Storage{
+		config:   config,
+		endpoint: endpoint,
+		folder:   folder,
+		now:      time.Now,
+	}, nil
+}
+
+func (s *S3Storage) Writer(objName string, helper storage.ParamsHelper) (storage.IWriteBroker, error) {
+	return s.broker(objName, helper), nil
+}
+
+func (s *S3Storage) Reader(objName string, helper storage.ParamsHelper) (storage.IReadBroker, error) {
+	return s.broker(objName, helper), nil
+}
+
+func (s *S3Storage) Manager(objName string, helper storage.ParamsHelper) (storage.IManageBroker, error) {
+	return s.broker(objName, helper), nil
+}
+
+// broker returns the broker of an object, the helper may name another bucket than the configured one.
+func (s *S3Storage) broker(objName string, helper storage.ParamsHelper) *S3Broker {
+	bucket := s.config.Bucket
+	if helper != nil {
+		if b, ok := helper().(string); ok && b != "" {
+			bucket = b
+		}
+	}
+	return &S3Broker{storage: s, bucket: bucket, key: s.key(objName)}
+}
+
+func (s *S3Storage) key(objName string) string {
+	return strings.TrimPrefix(path.Join(s.folder, objName), "/")
+}
+
+// objectURL returns the url of an object without query.
+func (s *S3Storage) objectURL(bucket, key string) *neturl.URL {
+	u := *s.endpoint
+	if s.config.PathStyle {
+		u.Path = "/" + bucket + "/" + key
+	} else {
+		u.Host = bucket + "." + u.Host
+		u.Path = "/" + key
+	}
+	u.RawPath = s3EscapePath(u.Path)
+	return &u
+}
+
+// do signs and sends a request for an object.
+func (s *S3Storage) do(method, bucket, key string, body []byte) (*http.Response, error) {
+	return s.doWithHeader(method, bucket, key, body, nil)
+}
+
+// doWithHeader is do with extra headers, such as the conditions of a request.
+func (s *S3Storage) doWithHeader(method, bucket, key string, body []byte, header http.Header) (*http.Response, error) {
+	return s.doWithQuery(method, bucket, key, nil, body, header)
+}
+
+// doWithQuery is doWithHeader with a query, such as the upload id of a multipart upload.
+func (s *S3Storage) doWithQuery(method, bucket, key string, query neturl.Values, body []byte, header http.Header) (*http.Response, error) {
+	u := s.objectURL(bucket, key)
+	u.RawQuery = s3CanonicalQuery(query)
+	req, err := http.NewRequest(method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	s.sign(req, s3Hash(body), s.now())
+	return s.config.Client.Do(req)
+}
+
+// sign adds the authorization header of signature v4 to req, signing the host and every header of req.
+// https://docs.aws.amazon.com/AmazonS3/latest/API/sig-v4-header-based-auth.html
+func (s *S3Storage) sign(req *http.Request, payloadHash string, t time.Time) {
+	t = t.UTC()
+	req.Header.Set("X-Amz-Date", t.Format(s3TimeFormat))
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if s.config.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.config.SessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for k, v := range req.Header {
+		headers[strings.ToLower(k)] = strings.TrimSpace(strings.Join(v, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, k := range names {
+		canonicalHeaders.WriteString(k + ":" + headers[k] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	scope := s.scope(t)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		s3EscapePath(req.URL.Path),
+		s3CanonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	signature := s.signature(t, canonicalRequest)
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s3Algorithm, s.config.AccessKey, scope, signedHeaders, signature))
+}
+
+// presign returns a url of an object that can be fetched without credentials until it expires.
+// https://docs.aws.amazon.com/AmazonS3/latest/API/sigv4-query-string-auth.html
+func (s *S3Storage) presign(method, bucket, key string, t time.Time, expires time.Duration) string {
+	t = t.UTC()
+	u := s.objectURL(bucket, key)
+	query := neturl.Values{}
+	query.Set("X-Amz-Algorithm", s3Algorithm)
+	query.Set("X-Amz-Credential", s.config.AccessKey+"/"+s.scope(t))
+	query.Set("X-Amz-Date", t.Format(s3TimeFormat))
+	query.Set("X-Amz-Expires", strconv.Itoa(int(expires/time.Second)))
+	query.Set("X-Amz-SignedHeaders", "host")
+	if s.config.SessionToken != "" {
+		query.Set("X-Amz-Security-Token", s.config.SessionToken)
+	}
+
+	canonicalRequest := strings.Join([]string{
+		method,
+		s3EscapePath(u.Path),
+		s3CanonicalQuery(query),
+		"host:" + u.Host + "\n",
+		"host",
+		s3UnsignedPayload,
+	}, "\n")
+	u.RawQuery = s3CanonicalQuery(query) + "&X-Amz-Signature=" + s.signature(t, canonicalRequest)
+	return u.String()
+}
+
+func (s *S3Storage) scope(t time.Time) string {
+	return t.Format(s3DateFormat) + "/" + s.config.Region + "/s3/aws4_request"
+}
+
+func (s *S3Storage) signature(t time.Time, canonicalRequest string) string {
+	stringToSign := strings.Join([]string{
+		s3Algorithm,
+		t.Format(s3TimeFormat),
+		s.scope(t),
+		s3Hash([]byte(canonicalRequest)),
+	}, "\n")
+	key := s3HMAC([]byte("AWS4"+s.config.SecretKey), t.Format(s3DateFormat))
+	key = s3HMAC(key, s.config.Region)
+	key = s3HMAC(key, "s3")
+	key = s3HMAC(key, "aws4_request")
+	return hex.EncodeToString(s3HMAC(key, stringToSign))
+}
+
+func s3HMAC(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+func s3Hash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// s3Escape encodes everything but the unreserved characters, as signature v4 asks.
+func s3Escape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func s3EscapePath(p string) string {
+	segments := strings.Split(p, "/")
+	for i, seg := range segments {
+		segments[i] = s3Escape(seg)
+	}
+	return strings.Join(segments, "/")
+}
+
+func s3CanonicalQuery(query neturl.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var pairs []string
+	for _, k := range keys {
+		values := append([]string{}, query[k]...)
+		sort.Strings(values)
+		for _, v := range values {
+			pairs = append(pairs, s3Escape(k)+"="+s3Escape(v))
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+func s3Error(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf("s3 %s %s err. %s %s", resp.Request.Method, resp.Request.URL.Path, resp.Status, strings.TrimSpace(string(body)))
+}
+
+var _ storage.IBroker = new(S3Broker)
+
+// S3Broker reads, writes and manages one object. Writes are uploaded in parts of s3PartSize as
+// OssMultipartBroker does, holding the first part until Close so WriteAt can rewrite it. An object
+// smaller than a part is uploaded with a single put on Close.
+type S3Broker struct {
+	storage  *S3Storage
+	bucket   string
+	key      string
+	uploadID string
+	parts    []s3Part
+	first    []byte
+	buf      *bytes.Buffer
+	err      error
+	stream   io.ReadCloser
+}
+
+type s3Part struct {
+	PartNumber int
+	ETag       string
+}
+
+func (broker *S3Broker) Write(p []byte) (int, error) {
+	if broker.err != nil {
+		return 0, broker.err
+	}
+	if broker.buf == nil {
+		broker.buf = &bytes.Buffer{}
+	}
+	broker.buf.Write(p)
+	for broker.buf.Len() >= s3PartSize {
+		if broker.first == nil {
+			broker.first = append([]byte{}, broker.buf.Next(s3PartSize)...)
+			continue
+		}
+		if err := broker.uploadPart(broker.buf.Next(s3PartSize), len(broker.parts)+2); err != nil {
+			broker.err = err
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// WriteAt rewrites what was written to the first part, such as the mp3 Xing frame.
+func (broker *S3Broker) WriteAt(p []byte, off int64) (int, error) {
+	if broker.err != nil {
+		return 0, broker.err
+	}
+	held := broker.first
+	if held == nil && broker.buf != nil {
+		held = broker.buf.Bytes()
+	}
+	if off < 0 || off+int64(len(p)) > int64(len(held)) {
+		return 0, errors.New("s3 broker can only rewrite the first part")
+	}
+	return copy(held[off:], p), nil
+}
+
+// https://docs.aws.amazon.com/AmazonS3/latest/API/API_CreateMultipartUpload.html
+func (broker *S3Broker) createUpload() error {
+	resp, err := broker.storage.doWithQuery(http.MethodPost, broker.bucket, broker.key, neturl.Values{"uploads": {""}}, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return s3Error(resp)
+	}
+	var result struct {
+		UploadId string
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("decode s3 multipart upload err. %s", err.Error())
+	}
+	if result.UploadId == "" {
+		return errors.New("s3 multipart upload has no upload id")
+	}
+	broker.uploadID = result.UploadId
+	return nil
+}
+
+// https://docs.aws.amazon.com/AmazonS3/latest/API/API_UploadPart.html
+func (broker *S3Broker) uploadPart(data []byte, number int) error {
+	if broker.uploadID == "" {
+		if err := broker.createUpload(); err != nil {
+			return err
+		}
+	}
+	query := neturl.Values{"partNumber": {strconv.Itoa(number)}, "uploadId": {broker.uploadID}}
+	resp, err := broker.storage.doWithQuery(http.MethodPut, broker.bucket, broker.key, query, data, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return s3Error(resp)
+	}
+	broker.parts = append(broker.parts, s3Part{PartNumber: number, ETag: resp.Header.Get("ETag")})
+	return nil
+}
+
+// https://docs.aws.amazon.com/AmazonS3/latest/API/API_CompleteMultipartUpload.html
+func (broker *S3Broker) completeUpload() error {
+	sort.Slice(broker.parts, func(i, j int) bool {
+		return broker.parts[i].PartNumber < broker.parts[j].PartNumber
+	})
+	body, err := xml.Marshal(struct {
+		XMLName xml.Name `xml:"CompleteMultipartUpload"`
+		Parts   []s3Part `xml:"Part"`
+	}{Parts: broker.parts})
+	if err != nil {
+		return err
+	}
+	resp, err := broker.storage.doWithQuery(http.MethodPost, broker.bucket, broker.key, neturl.Values{"uploadId": {broker.uploadID}}, body, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return s3Error(resp)
+	}
+	// the service may fail after sending 200, the error is then the body
+	result, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if bytes.Contains(result, []byte("<Error>")) {
+		return fmt.Errorf("s3 complete multipart upload err. %s", strings.TrimSpace(string(result)))
+	}
+	return nil
+}
+
+func (broker *S3Broker) Read(p []byte) (int, error) {
+	if broker.stream == nil {
+		resp, err := broker.storage.do(http.MethodGet, broker.bucket, broker.key, nil)
+		if err != nil {
+			return 0, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			defer resp.Body.Close()
+			return 0, s3Error(resp)
+		}
+		broker.stream = resp.Body
+	}
+	return broker.stream.Read(p)
+}
+
+// Close uploads what was written, or closes what was read. After a failed write it aborts the upload.
+func (broker *S3Broker) Close() error {
+	if broker.stream != nil {
+		err := broker.stream.Close()
+		broker.stream = nil
+		return err
+	}
+	if broker.err != nil {
+		err := broker.err
+		broker.Abort()
+		return err
+	}
+	if broker.buf == nil {
+		return nil
+	}
+	if broker.first == nil {
+		return broker.put()
+	}
+	var err error
+	if broker.buf.Len() > 0 {
+		err = broker.uploadPart(broker.buf.Bytes(), len(broker.parts)+2)
+	}
+	if err == nil {
+		err = broker.uploadPart(broker.first, 1)
+	}
+	if err == nil {
+		err = broker.completeUpload()
+	}
+	if err != nil {
+		broker.Abort()
+		return err
+	}
+	broker.reset()
+	return nil
+}
+
+// put uploads an object smaller than a part in a single request.
+func (broker *S3Broker) put() error {
+	resp, err := broker.storage.do(http.MethodPut, broker.bucket, broker.key, broker.buf.Bytes())
+	broker.reset()
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return s3Error(resp)
+	}
+	return nil
+}
+
+func (broker *S3Broker) reset() {
+	broker.uploadID = ""
+	broker.parts = nil
+	broker.first = nil
+	broker.buf = nil
+	broker.err = nil
+}
+
+// Abort discards what was written, the parts uploaded so far are deleted.
+// https://docs.aws.amazon.com/AmazonS3/latest/API/API_AbortMultipartUpload.html
+func (broker *S3Broker) Abort() error {
+	uploadID := broker.uploadID
+	broker.reset()
+	if uploadID == "" {
+		return nil
+	}
+	resp, err := broker.storage.doWithQuery(http.MethodDelete, broker.bucket, broker.key, neturl.Values{"uploadId": {uploadID}}, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		return s3Error(resp)
+	}
+	return nil
+}
+
+func (broker *S3Broker) Exist() (bool, error) {
+	_, ok, err := broker.stat()
+	return ok, err
+}
+
+// stat returns the size of the object and whether it exists.
+func (broker *S3Broker) stat() (int64, bool, error) {
+	resp, err := broker.storage.do(http.MethodHead, broker.bucket, broker.key, nil)
+	if err != nil {
+		return 0, false, err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp.ContentLength, true, nil
+	case http.StatusNotFound:
+		return 0, false, nil
+	}
+	return 0, false, s3Error(resp)
+}
+
+// URL returns a presigned url of the object, valid for S3Config.Expires.
+func (broker *S3Broker) URL() (string, error) {
+	return broker.storage.presign(http.MethodGet, broker.bucket, broker.key, broker.storage.now(), broker.storage.config.Expires), nil
+}
+
+// Delete deletes an object of the folder of the storage.
+func (broker *S3Broker) Delete(objName string) error {
+	resp, err := broker.storage.do(http.MethodDelete, broker.bucket, broker.storage.key(objName), nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		return s3Error(resp)
+	}
+	return nil
+}
+
+func NewS3SpeechService(config S3Config, folder string) (*SpeechService, error) {
+	s3Storage, err := NewS3Storage(config, folder)
+	if err != nil {
+		return nil, err
+	}
+	return newS3SpeechService(s3Storage), nil
+}
+
+func newS3SpeechService(s3Storage *S3Storage) *SpeechService {
+	return &SpeechService{
+		storage: s3Storage,
+		folder:  s3Storage.folder,
+		stat: func(key string) (int64, bool, error) {
+			return s3Storage.broker(key, nil).stat()
+		},
+	}
+}
+
+var _ ISpeech = new(S3Speech)
+
+type S3Speech struct {
+	*Speech
+	service *SpeechService
+}
+
+func NewS3Speech(c *edge.Communicate, config S3Config, folder string) (*S3Speech, error) {
+	s3Storage, err := NewS3Storage(config, folder)
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := NewSpeech(c, s3Storage, folder)
+	if err != nil {
+		return nil, err
+	}
+
+	return &S3Speech{
+		Speech:  s,
+		service: newS3SpeechService(s3Storage),
+	}, nil
+}
+
+func (speech *S3Speech) GenTTS() (string, func() error) {
+	return gentts(speech.Speech, speech.service)
+}
+
+func (speech *S3Speech) URL(filename string) (string, error) {
+	return objectURL(speech.IStorage, speech.service.helper, speech.Folder, filename, speech.URLOptions)
+}
@@ -1,9 +1,8 @@
 package edge_tts_go
 
 import (
-	"crypto/sha256"
-	"encoding/hex"
-	"fmt"
+	"context"
+	"time"
 
 	file_helper "github.com/pp-group/file-helper"
 	storage "github.com/pp-group/file-helper/storage"
@@ -20,6 +19,9 @@ var _ ISpeech = new(LocalSpeech)
 
 type LocalSpeech struct {
 	*Speech
+	// Retention counts the cache lookups of GenTTS, see SpeechService.Retention
+	Retention *Retention
+	service   *SpeechService
 }
 
 func NewLocalSpeech(c *edge.Communicate, folder string) (*LocalSpeech, error) {
@@ -34,30 +36,26 @@ func NewLocalSpeech(c *edge.Communicate, folder string) (*LocalSpeech, error) {
 	}
 
 	return &LocalSpeech{
-		Speech: s,
+		Speech:  s,
+		service: newLocalSpeechService(fileStorage, folder),
 	}, nil
 }
 
 func (speech *LocalSpeech) GenTTS() (string, func() error) {
-	fileName := speech.generateHashName() + ".mp3"
-	return fileName, func() error {
-		return gentts(speech.Speech, func() (storage.IWriteBroker, error) {
-			return speech.Writer(speech.FileName, nil)
-		})
-	}
+	speech.service.Retention = speech.Retention
+	return gentts(speech.Speech, speech.service)
 }
 
 func (speech *LocalSpeech) URL(filename string) (string, error) {
-	return url(func() (storage.IReadBroker, error) {
-		return speech.Reader(filename, nil)
-	})
+	return objectURL(speech.IStorage, speech.service.helper, speech.Folder, filename, speech.URLOptions)
 }
 
 var _ ISpeech = new(OssSpeech)
 
 type OssSpeech struct {
 	*Speech
-	bucket string
+	bucket  string
+	service *SpeechService
 }
 
 func NewOssSpeech(c *edge.Communicate, endpoint, ak, sk, folder, bucket string) (*OssSpeech, error) {
@@ -72,53 +70,37 @@ func NewOssSpeech(c *edge.Communicate, endpoint, ak, sk, folder, bucket string)
 	}
 
 	return &OssSpeech{
-		Speech: s,
-		bucket: bucket,
+		Speech:  s,
+		bucket:  bucket,
+		service: newOssSpeechService(ossStorage, folder, bucket),
 	}, nil
 }
 
 func (speech *OssSpeech) GenTTS() (string, func() error) {
-	fileName := speech.generateHashName() + ".mp3"
-	return fileName, func() error {
-		return gentts(speech.Speech, func() (storage.IWriteBroker, error) {
-			return speech.Writer(speech.FileName, func() interface{} {
-				return speech.bucket
-			})
-		})
-	}
+	return gentts(speech.Speech, speech.service)
 }
 
 func (speech *OssSpeech) URL(filename string) (string, error) {
-	return url(func() (storage.IReadBroker, error) {
-		return speech.Reader(filename, func() interface{} {
-			return speech.bucket
-		})
-	})
+	return objectURL(speech.IStorage, speech.service.helper, speech.Folder, filename, speech.URLOptions)
 }
 
-func gentts(speech *Speech, brokerFunc func() (storage.IWriteBroker, error)) error {
-	fileName := speech.generateHashName() + ".mp3"
-
+// gentts names the object once and returns the callback that synthesizes into it, unless the object is
+// already stored and ForceRefresh is not set. Synthesize of SpeechService does the same without a Speech.
+func gentts(speech *Speech, service *SpeechService) (string, func() error) {
+	fileName := speech.generateFileName()
 	speech.FileName = fileName
-
-	broker, err := brokerFunc()
-	if err != nil {
-		return err
-	}
-
-	err = speech.gen(broker)
-	if err != nil {
-		return err
-	}
-	return nil
-}
-
-func url(brokerFunc func() (storage.IReadBroker, error)) (string, error) {
-	broker, err := brokerFunc()
-	if err != nil {
-		return "", err
+	service.Lease = speech.Lease
+	return fileName, func() error {
+		result, err := service.synthesize(context.Background(), speech.Communicate, fileName, synthesisJob{
+			forceRefresh: speech.ForceRefresh,
+			subtitles:    speech.Subtitles,
+		})
+		if err != nil {
+			return err
+		}
+		speech.Audio, speech.Chunks = result.Audio, result.Chunks
+		return nil
 	}
-	return broker.URL()
 }
 
 type Speech struct {
@@ -126,6 +108,17 @@ type Speech struct {
 	storage.IStorage
 	Folder   string
 	FileName string
+	// ForceRefresh synthesizes again even if the object is already stored
+	ForceRefresh bool
+	// Subtitles are the subtitle formats GenTTS stores next to the audio, named <file name>.<format>
+	Subtitles []string
+	// URLOptions controls the urls returned by URL
+	URLOptions URLOptions
+	// Lease makes other processes wait for GenTTS of the same audio, see SpeechService.Lease
+	Lease time.Duration
+	// Audio describes the stored audio and Chunks the audio of each message of it, once GenTTS is done
+	Audio  AudioInfo
+	Chunks []AudioInfo
 }
 
 func NewSpeech(c *edge.Communicate, storage storage.IStorage, folder string) (*Speech, error) {
@@ -137,43 +130,23 @@ func NewSpeech(c *edge.Communicate, storage storage.IStorage, folder string) (*S
 	return s, nil
 }
 
-func (s *Speech) generateHashName() string {
-	hash := sha256.Sum256([]byte(s.Text + s.Rate + s.Volume + s.Pitch))
-	return fmt.Sprintf("%s_%s", s.VoiceLangRegion, hex.EncodeToString(hash[:]))
+// generateFileName names the object by the voice and the cache key of the Communicate,
+// which covers every option that changes the audio.
+func (s *Speech) generateFileName() string {
+	return Key(s.Communicate)
 }
 
+// gen writes the audio to the broker and closes it, on failure the writes are discarded.
 func (s *Speech) gen(broker storage.IWriteBroker) error {
-	op, err := s.Stream()
+	synthesized, err := synthesize(context.Background(), s.Communicate, broker)
 	if err != nil {
+		abortWrite(broker)
 		return err
 	}
-	defer s.CloseOutput()
-	solveCount := 0
-	audioData := make([][][]byte, s.AudioDataIndex)
-	for i := range op {
-		if _, ok := i["end"]; ok {
-			solveCount++
-			if solveCount == s.AudioDataIndex {
-				break
-			}
-		}
-		t, ok := i["type"]
-		if ok && t == "audio" {
-			data := i["data"].(edge.AudioData)
-			audioData[data.Index] = append(audioData[data.Index], data.Data)
-		}
-		e, ok := i["error"]
-		if ok {
-			fmt.Printf("has error err: %v\n", e)
-		}
-	}
-	// write data, sort by index
-	for _, v := range audioData {
-		for _, data := range v {
-			broker.Write(data)
-		}
+	if err := closeWrite(broker); err != nil {
+		return err
 	}
-	broker.Close()
+	s.Audio, s.Chunks = synthesized.audio, synthesized.chunks
 	return nil
 }
 
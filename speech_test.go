@@ -1,6 +1,10 @@
 package edge_tts_go
 
 import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
 	"testing"
 
 	"github.com/pp-group/edge-tts-go/biz/service/tts/edge"
@@ -36,3 +40,59 @@ func genTTS(text string) (string, error) {
 
 	return speech.URL(speech.FileName)
 }
+
+func TestGenTTSCached(t *testing.T) {
+	c, err := edge.NewCommunicate("cached")
+	if err != nil {
+		t.Fatalf("NewCommunicate fail, err: %v", err)
+	}
+	folder := t.TempDir()
+	speech, err := NewLocalSpeech(c, folder)
+	if err != nil {
+		t.Fatalf("NewLocalSpeech fail, err: %v", err)
+	}
+	fileName, callback := speech.GenTTS()
+	if err := os.WriteFile(filepath.Join(folder, fileName), []byte("audio"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// the object exists, so no connection to the service is made
+	if err := callback(); err != nil {
+		t.Errorf("cached GenTTS fail, err: %v", err)
+	}
+	if speech.FileName != fileName {
+		t.Errorf("FileName = %s, want %s", speech.FileName, fileName)
+	}
+}
+
+func TestSynthesizeCached(t *testing.T) {
+	folder := t.TempDir()
+	service, err := NewLocalSpeechService(folder)
+	if err != nil {
+		t.Fatalf("NewLocalSpeechService fail, err: %v", err)
+	}
+	req := Request{Text: "cached", Options: []edge.Option{edge.WithVoice("en-US-AriaNeural")}}
+	c, err := edge.NewCommunicate(req.Text, req.Options...)
+	if err != nil {
+		t.Fatalf("NewCommunicate fail, err: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(folder, Key(c)), make([]byte, 12000), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := service.Synthesize(context.Background(), req)
+			if err != nil {
+				t.Errorf("Synthesize fail, err: %v", err)
+				return
+			}
+			if !result.Cached || result.Key != Key(c) || result.Size != 12000 || result.Duration != 2 || result.Format != edge.DefaultOutputFormat {
+				t.Errorf("unexpected result: %+v", result)
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,69 @@
+package edge_tts_go
+
+import (
+	"errors"
+
+	"github.com/pp-group/edge-tts-go/biz/service/tts/edge"
+)
+
+// errNoBoundaries tells that a cached object has no metadata to make its subtitles from.
+var errNoBoundaries = errors.New("no boundaries stored")
+
+// SubtitleKey returns the key of the subtitle or word timing sidecar of an audio object in a format.
+func SubtitleKey(key, format string) string {
+	return key + "." + format
+}
+
+// writeSubtitles stores the subtitles of the result in every format. The boundaries of a cached object
+// come from its metadata, errNoBoundaries is returned when it can not be read. Subtitles that are already
+// stored are kept.
+func (s *SpeechService) writeSubtitles(c *edge.Communicate, result *Result, formats []string) error {
+	if len(formats) == 0 {
+		return nil
+	}
+	result.Subtitles = map[string]string{}
+	boundaries := result.Boundaries
+	for _, format := range formats {
+		subKey := SubtitleKey(result.Key, format)
+		result.Subtitles[format] = subKey
+		if result.Cached {
+			_, ok, err := s.stat(subKey)
+			if err != nil {
+				return err
+			}
+			if ok {
+				continue
+			}
+			if boundaries == nil {
+				m, err := s.Metadata(result.Key)
+				if err != nil {
+					return errNoBoundaries
+				}
+				boundaries = append(append(boundaries, m.Sentences...), m.Words...)
+			}
+		}
+
+		var doc []byte
+		if format == edge.SubtitleReadAlong {
+			data, err := c.ReadAlong(boundaries).JSON()
+			if err != nil {
+				return err
+			}
+			doc = data
+		} else {
+			maker := edge.NewSubMaker(s.SubtitleOptions)
+			for _, b := range boundaries {
+				maker.Feed(b)
+			}
+			data, err := maker.Subtitle(format)
+			if err != nil {
+				return err
+			}
+			doc = []byte(data)
+		}
+		if err := writeObject(s.storage, s.helper, s.folder, subKey, doc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package edge_tts_go
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pp-group/edge-tts-go/biz/service/tts/edge"
+)
+
+func TestSubtitleSidecars(t *testing.T) {
+	memory := NewMemoryStorage()
+	service := NewMemorySpeechService(memory)
+	req := Request{Text: "Hello world.", Options: []edge.Option{edge.WithVoice("en-US-AriaNeural")}, Subtitles: []string{edge.SubtitleSRT, edge.SubtitleWebVTT, edge.SubtitleReadAlong}}
+	c, err := edge.NewCommunicate(req.Text, req.Options...)
+	if err != nil {
+		t.Fatalf("NewCommunicate fail, err: %v", err)
+	}
+
+	// a cached object gets its subtitles from the boundaries in its metadata
+	result := &Result{Key: Key(c), Format: c.OutputFormat, Size: 6000, Boundaries: []edge.Boundary{
+		{Type: edge.BoundaryWord, Offset: 100 * time.Millisecond, Duration: 400 * time.Millisecond, Text: "Hello"},
+		{Type: edge.BoundaryWord, Offset: 600 * time.Millisecond, Duration: 500 * time.Millisecond, Text: "world"},
+	}}
+	writeObject(memory, nil, "", result.Key, make([]byte, 6000))
+	if err := service.writeMetadata(newMetadata(c, result, false)); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := service.Synthesize(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Synthesize fail, err: %v", err)
+	}
+	srt, ok := memory.Bytes(got.Subtitles[edge.SubtitleSRT])
+	if !ok || string(srt) != "1\n00:00:00,100 --> 00:00:01,100\nHello world\n\n" {
+		t.Errorf("srt = %q", srt)
+	}
+	if _, ok := memory.Bytes(Key(c) + ".vtt"); !ok {
+		t.Errorf("vtt sidecar missing, subtitles %v", got.Subtitles)
+	}
+
+	if doc, _ := memory.Bytes(got.Subtitles[edge.SubtitleReadAlong]); !strings.Contains(string(doc), `"char_start": 6`) {
+		t.Errorf("read-along = %s", doc)
+	}
+
+	// an object stored without metadata is synthesized again for its boundaries
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	bare := Request{Text: "No metadata.", Subtitles: []string{edge.SubtitleSRT}}
+	c, _ = edge.NewCommunicate(bare.Text)
+	writeObject(memory, nil, "", Key(c), make([]byte, 6000))
+	if _, err := service.Synthesize(ctx, bare); !errors.Is(err, context.Canceled) {
+		t.Errorf("Synthesize without metadata err: %v", err)
+	}
+
+	req.Subtitles = []string{"ass"}
+	if _, err := service.Synthesize(context.Background(), req); err == nil {
+		t.Errorf("unknown subtitle format should fail")
+	}
+}
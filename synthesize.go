@@ -0,0 +1,443 @@
+package edge_tts_go
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	file_helper "github.com/pp-group/file-helper"
+	storage "github.com/pp-group/file-helper/storage"
+
+	"github.com/pp-group/edge-tts-go/biz/service/tts/edge"
+)
+
+// Request is one text to synthesize with its options, Synthesize does not change it.
+type Request struct {
+	Text    string
+	Options []edge.Option
+	// ForceRefresh synthesizes again even if the object is already stored
+	ForceRefresh bool
+	// Subtitles are the subtitle and word timing formats stored next to the audio, see edge.SubtitleSRT,
+	// edge.SubtitleWebVTT, edge.SubtitleLRC and edge.SubtitleReadAlong
+	Subtitles []string
+}
+
+// Result describes the stored audio of a request, Duration is in seconds.
+type Result struct {
+	Key        string
+	URL        string
+	Size       int64
+	Duration   float64
+	Format     string
+	Boundaries []edge.Boundary
+	// Audio describes the stored audio and Chunks the audio of each message of it, in their order
+	Audio  AudioInfo
+	Chunks []AudioInfo
+	// Cached tells that the object was already stored and the service was not called, Boundaries are then empty
+	Cached bool
+	// Shared tells that the audio was stored for a concurrent identical request of this process
+	Shared bool
+	// Subtitles maps each requested subtitle format to the key of its object
+	Subtitles map[string]string
+}
+
+// SpeechService synthesizes requests into one storage. It keeps no state of a request,
+// so one service can handle many requests at once.
+type SpeechService struct {
+	storage storage.IStorage
+	helper  storage.ParamsHelper
+	folder  string
+	// stat returns the size of a stored object and whether it exists
+	stat func(key string) (int64, bool, error)
+
+	// RedactText keeps only the hash of the text in the metadata sidecar of each object
+	RedactText bool
+	// SubtitleOptions limits the cues of the subtitle sidecars
+	SubtitleOptions edge.SubtitleOptions
+	// URLOptions controls the urls of the results
+	URLOptions URLOptions
+	// Retention counts the cache hits and misses of a local folder and marks the hit objects as used
+	Retention *Retention
+	// Lease is how long a process holds the LeaseKey object of the audio it synthesizes, other processes
+	// sharing the storage wait for it instead of synthesizing the same audio. It should be longer than
+	// the longest synthesis, 0 disables the lease.
+	Lease time.Duration
+}
+
+func NewLocalSpeechService(folder string) (*SpeechService, error) {
+	fileStorage, err := file_helper.FileStorageFactory(folder)()
+	if err != nil {
+		return nil, err
+	}
+	return newLocalSpeechService(fileStorage, folder), nil
+}
+
+func newLocalSpeechService(fileStorage storage.IStorage, folder string) *SpeechService {
+	s := &SpeechService{storage: fileStorage, folder: folder}
+	// the local reader would create an empty file, so the file is checked directly
+	s.stat = func(key string) (int64, bool, error) {
+		info, err := os.Stat(filepath.Join(folder, key))
+		if os.IsNotExist(err) {
+			return 0, false, nil
+		}
+		if err != nil {
+			return 0, false, err
+		}
+		return info.Size(), info.Size() > 0, nil
+	}
+	return s
+}
+
+func NewOssSpeechService(endpoint, ak, sk, folder, bucket string) (*SpeechService, error) {
+	ossStorage, err := file_helper.OssStorageFactory(endpoint, ak, sk, folder)()
+	if err != nil {
+		return nil, err
+	}
+	return newOssSpeechService(ossStorage, folder, bucket), nil
+}
+
+func newOssSpeechService(ossStorage storage.IStorage, folder, bucket string) *SpeechService {
+	helper := func() interface{} {
+		return bucket
+	}
+	s := &SpeechService{storage: ossStorage, helper: helper, folder: folder}
+	s.stat = func(key string) (int64, bool, error) {
+		if client, ok := ossStorage.(*storage.OssStorage); ok {
+			b, err := client.Bucket(bucket)
+			if err != nil {
+				return 0, false, err
+			}
+			ok, err := b.IsObjectExist(path.Join(folder, key))
+			if err != nil || !ok {
+				return 0, false, err
+			}
+			header, err := b.GetObjectDetailedMeta(path.Join(folder, key))
+			if err != nil {
+				return 0, false, err
+			}
+			size, _ := strconv.ParseInt(header.Get("Content-Length"), 10, 64)
+			return size, true, nil
+		}
+		broker, err := ossStorage.Reader(key, helper)
+		if err != nil {
+			return 0, false, err
+		}
+		ok, err := broker.Exist()
+		return 0, ok, err
+	}
+	return s
+}
+
+// Key returns the object name of the audio of c: the voice, the cache key and the file extension.
+func Key(c *edge.Communicate) string {
+	return fmt.Sprintf("%s_%s.%s", c.VoiceLangRegion, c.CacheKey(), c.FileExtension())
+}
+
+// Synthesize stores the audio of the request under its Key with its metadata, unless it is already stored.
+func (s *SpeechService) Synthesize(ctx context.Context, req Request) (*Result, error) {
+	c, err := edge.NewCommunicate(req.Text, req.Options...)
+	if err != nil {
+		return nil, err
+	}
+	return s.synthesize(ctx, c, Key(c), synthesisJob{forceRefresh: req.ForceRefresh, subtitles: req.Subtitles})
+}
+
+// URL returns the url of a stored object, see URLOptions.
+func (s *SpeechService) URL(key string) (string, error) {
+	return objectURL(s.storage, s.helper, s.folder, key, s.URLOptions)
+}
+
+// synthesisJob is what a request asks besides the audio.
+type synthesisJob struct {
+	forceRefresh bool
+	subtitles    []string
+}
+
+func (s *SpeechService) synthesize(ctx context.Context, c *edge.Communicate, key string, job synthesisJob) (*Result, error) {
+	for _, format := range job.subtitles {
+		switch format {
+		case edge.SubtitleSRT, edge.SubtitleWebVTT, edge.SubtitleLRC, edge.SubtitleReadAlong:
+		default:
+			return nil, fmt.Errorf("unknown subtitle format %s", format)
+		}
+	}
+
+	result, err := s.storeShared(ctx, c, key, job.forceRefresh)
+	if err != nil {
+		return nil, err
+	}
+	err = s.writeSubtitles(c, &result, job.subtitles)
+	if err == errNoBoundaries {
+		// stored without its metadata, such as audiobook chapters, the boundaries come from synthesizing it again
+		if result, err = s.storeShared(ctx, c, key, true); err != nil {
+			return nil, err
+		}
+		err = s.writeSubtitles(c, &result, job.subtitles)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	url, err := s.URL(key)
+	if err != nil {
+		return nil, err
+	}
+	result.URL = url
+	return &result, nil
+}
+
+// storeShared is store shared with the callers storing the same key at the same time.
+func (s *SpeechService) storeShared(ctx context.Context, c *edge.Communicate, key string, forceRefresh bool) (Result, error) {
+	stored, shared, err := synthesisFlights.do(ctx, flightKey(s, key, forceRefresh), func() (*Result, error) {
+		return s.store(ctx, c, key, forceRefresh)
+	})
+	if err != nil {
+		return Result{}, err
+	}
+	result := *stored
+	result.Shared = shared
+	return result, nil
+}
+
+// store stores the audio of c under key with its metadata, unless it is already stored. With a Lease,
+// it waits for another process synthesizing the same key instead of synthesizing it again.
+func (s *SpeechService) store(ctx context.Context, c *edge.Communicate, key string, forceRefresh bool) (*Result, error) {
+	result := &Result{Key: key, Format: c.OutputFormat}
+	if !forceRefresh {
+		size, ok, err := s.stat(key)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			result.Cached = true
+			result.Size = size
+		}
+		if s.Retention != nil {
+			if ok {
+				s.Retention.hit(key)
+			} else {
+				s.Retention.miss()
+			}
+		}
+	}
+
+	if !result.Cached && s.Lease > 0 {
+		size, release, err := s.acquireLease(ctx, key, forceRefresh)
+		if err != nil {
+			return nil, err
+		}
+		if release == nil {
+			result.Cached = true
+			result.Size = size
+		} else {
+			defer release()
+		}
+	}
+
+	if !result.Cached {
+		broker, err := newWriter(s.storage, s.helper, s.folder, key)
+		if err != nil {
+			return nil, err
+		}
+		synthesized, err := synthesize(ctx, c, broker)
+		if err != nil {
+			abortWrite(broker)
+			return nil, err
+		}
+		if err := closeWrite(broker); err != nil {
+			return nil, err
+		}
+		result.Size = synthesized.size
+		result.Boundaries = synthesized.boundaries
+		result.Audio = synthesized.audio
+		result.Chunks = synthesized.chunks
+	} else if m, err := s.Metadata(key); err == nil && m.Audio.Size == result.Size {
+		result.Audio, result.Chunks = m.Audio, m.Chunks
+	} else {
+		// stored without its audio info
+		result.Audio = estimateAudioInfo(c.OutputFormat, result.Size)
+	}
+	result.Duration = result.Audio.Duration
+	if !result.Cached {
+		if err := s.writeMetadata(newMetadata(c, result, s.RedactText)); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// synthesis is what synthesize wrote, size counts the bytes given to its writer.
+type synthesis struct {
+	size       int64
+	boundaries []edge.Boundary
+	audio      AudioInfo
+	chunks     []AudioInfo
+}
+
+// synthesize streams c and writes its audio to w in the order of the messages, see orderedWriter.
+// Boundary offsets are shifted by the duration of the audio of the messages before theirs.
+func synthesize(ctx context.Context, c *edge.Communicate, w io.Writer) (synthesis, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	events, n, err := c.StreamContext(ctx)
+	if err != nil {
+		return synthesis{}, err
+	}
+
+	// the frames of mp3 messages are joined under one Xing frame
+	var mp3 *mp3Writer
+	if isMP3(c.OutputFormat) {
+		mp3 = newMP3Writer(w)
+		w = mp3
+	}
+	ordered := newOrderedWriter(w, n)
+	boundaries := make([][]edge.Boundary, n)
+	var streamErr error
+	for event := range events {
+		if e, ok := event["error"]; ok {
+			if streamErr == nil {
+				streamErr = fmt.Errorf("%+v", e)
+			}
+			cancel()
+			continue
+		}
+		if _, ok := event["end"]; ok {
+			if err := ordered.end(event["index"].(int)); err != nil && streamErr == nil {
+				streamErr = err
+				cancel()
+			}
+			continue
+		}
+		switch event["type"] {
+		case "audio":
+			data := event["data"].(edge.AudioData)
+			if err := ordered.audio(data.Index, data.Data); err != nil && streamErr == nil {
+				streamErr = err
+				cancel()
+			}
+		case edge.BoundaryWord, edge.BoundarySentence:
+			if idx, b, ok := edge.BoundaryEvent(event); ok {
+				boundaries[idx] = append(boundaries[idx], b)
+			}
+		}
+	}
+	result := synthesis{size: ordered.size}
+	if streamErr != nil {
+		return result, streamErr
+	}
+	if err := ctx.Err(); err != nil {
+		return result, err
+	}
+	if !ordered.done() {
+		return result, errors.New("stream ended before every message")
+	}
+	if mp3 != nil {
+		if err := mp3.finish(); err != nil {
+			return result, err
+		}
+		result.size = mp3.size()
+	}
+
+	var shift time.Duration
+	for idx := range boundaries {
+		for _, b := range boundaries[idx] {
+			b.Offset += shift
+			result.boundaries = append(result.boundaries, b)
+		}
+		var chunk AudioInfo
+		if mp3 != nil {
+			chunk = mp3.streams[idx].info()
+		} else {
+			chunk = chunkAudioInfo(c.OutputFormat, ordered.heads[idx], ordered.sizes[idx])
+		}
+		result.chunks = append(result.chunks, chunk)
+		shift += time.Duration(chunk.Duration * float64(time.Second))
+	}
+	sort.SliceStable(result.boundaries, func(i, j int) bool {
+		return result.boundaries[i].Offset < result.boundaries[j].Offset
+	})
+	result.audio = mergeAudioInfo(result.chunks, result.size)
+	return result, nil
+}
+
+// orderedWriter writes the audio of the messages in their order as soon as every message before is done,
+// only the audio of messages that arrive early is held in memory.
+type orderedWriter struct {
+	w io.Writer
+	// next is the message written to w, the audio of later messages waits in pending
+	next    int
+	pending [][]byte
+	ended   []bool
+	sizes   []int64
+	size    int64
+	// heads are the first bytes of the audio of each message, see audioHeadSize
+	heads [][]byte
+	err   error
+}
+
+func newOrderedWriter(w io.Writer, n int) *orderedWriter {
+	return &orderedWriter{
+		w:       w,
+		pending: make([][]byte, n),
+		ended:   make([]bool, n),
+		sizes:   make([]int64, n),
+		heads:   make([][]byte, n),
+	}
+}
+
+func (o *orderedWriter) audio(idx int, data []byte) error {
+	if idx == o.next {
+		return o.write(idx, data)
+	}
+	o.pending[idx] = append(o.pending[idx], data...)
+	return o.err
+}
+
+// streamEnder is a writer that needs to know where the audio of each message ends, see mp3Writer.
+type streamEnder interface {
+	endStream() error
+}
+
+// end marks a message as done and writes the audio of the messages that were waiting for it.
+func (o *orderedWriter) end(idx int) error {
+	o.ended[idx] = true
+	for o.next < len(o.ended) && o.ended[o.next] {
+		if e, ok := o.w.(streamEnder); ok && o.err == nil {
+			o.err = e.endStream()
+		}
+		o.next++
+		if o.next < len(o.ended) {
+			o.write(o.next, o.pending[o.next])
+			o.pending[o.next] = nil
+		}
+	}
+	return o.err
+}
+
+func (o *orderedWriter) write(idx int, data []byte) error {
+	if o.err != nil || len(data) == 0 {
+		return o.err
+	}
+	if keep := audioHeadSize - len(o.heads[idx]); keep > 0 {
+		if keep > len(data) {
+			keep = len(data)
+		}
+		o.heads[idx] = append(o.heads[idx], data[:keep]...)
+	}
+	written, err := o.w.Write(data)
+	o.size += int64(written)
+	o.sizes[idx] += int64(written)
+	o.err = err
+	return err
+}
+
+func (o *orderedWriter) done() bool {
+	return o.next == len(o.ended)
+}
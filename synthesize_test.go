@@ -0,0 +1,31 @@
+package edge_tts_go
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestOrderedWriter(t *testing.T) {
+	var buf bytes.Buffer
+	o := newOrderedWriter(&buf, 3)
+	o.audio(2, []byte("e"))
+	o.audio(1, []byte("c"))
+	o.audio(0, []byte("a"))
+	if buf.String() != "a" {
+		t.Errorf("only the first message should be written, got %q", buf.String())
+	}
+	o.audio(1, []byte("d"))
+	o.end(2)
+	o.audio(0, []byte("b"))
+	o.end(0)
+	if buf.String() != "abcd" {
+		t.Errorf("the second message should follow the first, got %q", buf.String())
+	}
+	if len(o.pending[1]) != 0 || string(o.pending[2]) != "e" {
+		t.Errorf("written audio should not be kept, pending %q", o.pending)
+	}
+	o.end(1)
+	if buf.String() != "abcde" || !o.done() || o.size != 5 || o.sizes[1] != 2 {
+		t.Errorf("got %q, sizes %v", buf.String(), o.sizes)
+	}
+}
@@ -0,0 +1,151 @@
+package edge_tts_go
+
+import (
+	"errors"
+	"fmt"
+	neturl "net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aliyun/aliyun-oss-go-sdk/oss"
+	storage "github.com/pp-group/file-helper/storage"
+)
+
+// URLOptions controls the urls returned for stored objects.
+type URLOptions struct {
+	// Expires is the lifetime of signed oss and s3 urls, by default 300s for oss and S3Config.Expires for s3
+	Expires time.Duration
+	// CDNHost replaces the scheme and host of the urls, such as https://cdn.example.com or https://cdn.example.com/tts,
+	// the path of the object and the signature are kept. Signed oss and s3 urls can not take a path prefix, the
+	// signature covers the path.
+	CDNHost string
+	// LocalBaseURL turns the paths of local files into urls under it, such as /audio or https://gateway.example.com/audio,
+	// by default the path of the file is returned
+	LocalBaseURL string
+}
+
+func (opts URLOptions) validate() error {
+	if opts.Expires < 0 || opts.Expires > s3MaxExpires {
+		return errors.New("url expires must be between 0 and 7 days")
+	}
+	if opts.Expires > 0 && opts.Expires < time.Second {
+		return errors.New("url expires must be at least 1s")
+	}
+	if opts.CDNHost != "" {
+		u, err := neturl.Parse(opts.CDNHost)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("invalid cdn host %s", opts.CDNHost)
+		}
+	}
+	return nil
+}
+
+// objectURL returns the url of a stored object, signed for the private oss and s3 buckets.
+func objectURL(s storage.IStorage, helper storage.ParamsHelper, folder, key string, opts URLOptions) (string, error) {
+	if err := opts.validate(); err != nil {
+		return "", err
+	}
+
+	var u string
+	signed := false
+	switch st := s.(type) {
+	case *storage.FileStorage:
+		path := filepath.Join(folder, key)
+		if _, err := os.Stat(path); err != nil {
+			return "", err
+		}
+		if opts.LocalBaseURL == "" {
+			return path, nil
+		}
+		u = strings.TrimRight(opts.LocalBaseURL, "/") + "/" + escapeKey(key)
+	case *storage.OssStorage:
+		if helper == nil {
+			return "", errors.New("oss storage must need a paramsHelper to specify buckert name")
+		}
+		bucket, err := st.Bucket(helper().(string))
+		if err != nil {
+			return "", err
+		}
+		objKey := path.Join(folder, key)
+		ok, err := bucket.IsObjectExist(objKey)
+		if err != nil {
+			return "", err
+		}
+		if !ok {
+			return "", os.ErrNotExist
+		}
+		expires := int64(ossURLExpires)
+		if opts.Expires > 0 {
+			expires = int64(opts.Expires / time.Second)
+		}
+		if u, err = bucket.SignURL(objKey, oss.HTTPGet, expires); err != nil {
+			return "", err
+		}
+		signed = true
+	case *S3Storage:
+		expires := st.config.Expires
+		if opts.Expires > 0 {
+			expires = opts.Expires
+		}
+		broker := st.broker(key, helper)
+		if _, ok, err := broker.stat(); err != nil || !ok {
+			if err == nil {
+				err = os.ErrNotExist
+			}
+			return "", err
+		}
+		u = st.presign("GET", broker.bucket, broker.key, st.now(), expires)
+		signed = true
+	default:
+		broker, err := s.Reader(key, helper)
+		if err != nil {
+			return "", err
+		}
+		if u, err = broker.URL(); err != nil {
+			return "", err
+		}
+	}
+	return rewriteHost(u, opts.CDNHost, signed)
+}
+
+// rewriteHost puts rawURL behind cdnHost, keeping its path and query. The path of cdnHost prefixes the
+// path of rawURL, unless rawURL is signed.
+func rewriteHost(rawURL, cdnHost string, signed bool) (string, error) {
+	if cdnHost == "" {
+		return rawURL, nil
+	}
+	u, err := neturl.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" && !(u.Scheme == "" && strings.HasPrefix(u.Path, "/")) {
+		// urls such as mem:// can not be served by a cdn
+		return rawURL, nil
+	}
+	cdn, err := neturl.Parse(cdnHost)
+	if err != nil {
+		return "", err
+	}
+	u.Scheme, u.Host = cdn.Scheme, cdn.Host
+	if prefix := strings.TrimRight(cdn.Path, "/"); prefix != "" {
+		if signed {
+			return "", fmt.Errorf("cdn host %s has a path, signed urls must keep theirs", cdnHost)
+		}
+		u.Path = prefix + u.Path
+		if u.RawPath != "" {
+			u.RawPath = strings.TrimRight(cdn.EscapedPath(), "/") + u.RawPath
+		}
+	}
+	return u.String(), nil
+}
+
+func escapeKey(key string) string {
+	segments := strings.Split(filepath.ToSlash(key), "/")
+	for i, seg := range segments {
+		segments[i] = neturl.PathEscape(seg)
+	}
+	return strings.Join(segments, "/")
+}
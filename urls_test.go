@@ -0,0 +1,56 @@
+package edge_tts_go
+
+import (
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	file_helper "github.com/pp-group/file-helper"
+)
+
+func TestObjectURL(t *testing.T) {
+	folder := t.TempDir()
+	fileStorage, _ := file_helper.FileStorageFactory(folder)()
+	os.WriteFile(filepath.Join(folder, "a b.mp3"), []byte("audio"), 0o644)
+
+	cases := []struct {
+		opts URLOptions
+		want string
+	}{
+		{URLOptions{}, filepath.Join(folder, "a b.mp3")},
+		{URLOptions{LocalBaseURL: "/audio/"}, "/audio/a%20b.mp3"},
+		{URLOptions{LocalBaseURL: "/audio", CDNHost: "https://cdn.example.com/tts"}, "https://cdn.example.com/tts/audio/a%20b.mp3"},
+	}
+	for _, c := range cases {
+		if got, err := objectURL(fileStorage, nil, folder, "a b.mp3", c.opts); err != nil || got != c.want {
+			t.Errorf("objectURL(%+v) = %s, err: %v, want %s", c.opts, got, err, c.want)
+		}
+	}
+	if _, err := objectURL(fileStorage, nil, folder, "missing.mp3", URLOptions{}); err == nil {
+		t.Errorf("missing object should fail")
+	}
+	if _, err := objectURL(fileStorage, nil, folder, "a b.mp3", URLOptions{Expires: 8 * 24 * time.Hour}); err == nil {
+		t.Errorf("too long expires should fail")
+	}
+
+	fake := &fakeS3{objects: map[string][]byte{"/audio/tts/a.mp3": []byte("audio")}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	s3Storage, _ := NewS3Storage(S3Config{Endpoint: server.URL, Bucket: "audio", AccessKey: "minio", SecretKey: "minio123", PathStyle: true}, "tts")
+	got, err := objectURL(s3Storage, nil, "tts", "a.mp3", URLOptions{Expires: time.Minute, CDNHost: "https://cdn.example.com"})
+	if err != nil || !strings.HasPrefix(got, "https://cdn.example.com/audio/tts/a.mp3?") || !strings.Contains(got, "X-Amz-Expires=60&") {
+		t.Errorf("s3 url = %s, err: %v", got, err)
+	}
+
+	// the signature covers the path
+	if got, err := objectURL(s3Storage, nil, "tts", "a.mp3", URLOptions{CDNHost: "https://cdn.example.com/tts"}); err == nil {
+		t.Errorf("s3 url behind a cdn path = %s", got)
+	}
+
+	if got, _ := rewriteHost("mem://a.mp3", "https://cdn.example.com", false); got != "mem://a.mp3" {
+		t.Errorf("mem url should be kept, got %s", got)
+	}
+}